@@ -0,0 +1,119 @@
+package cli
+
+import (
+	"os"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+func NewAppCenterCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "appcenter <subcommand>",
+		Short: "Work with Waldo from App Center builds."}
+
+	cmd.AddCommand(fixup(newAppCenterBuildAndUploadCommand()))
+
+	return cmd
+}
+
+//-----------------------------------------------------------------------------
+
+func applyUploadFlagsFromEnv(cmd *cobra.Command, name string) error {
+	fields := strings.Fields(os.Getenv(name))
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	envFlags := pflag.NewFlagSet(name, pflag.ContinueOnError)
+
+	addUploadFlags(envFlags, &waldo.UploadOptions{})
+
+	if err := envFlags.Parse(fields); err != nil {
+		return err
+	}
+
+	var err error
+
+	//
+	// Options given on the command line take precedence over those from the
+	// environment:
+	//
+	envFlags.Visit(func(flag *pflag.Flag) {
+		if err != nil || cmd.Flags().Changed(flag.Name) {
+			return
+		}
+
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, item := range sv.GetSlice() {
+				if err = cmd.Flags().Set(flag.Name, item); err != nil {
+					return
+				}
+			}
+		} else {
+			err = cmd.Flags().Set(flag.Name, flag.Value.String())
+		}
+	})
+
+	return err
+}
+
+func newAppCenterBuildAndUploadCommand() *cobra.Command {
+	options := &waldo.AppCenterBuildOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "build-and-upload [<upload-options>]",
+		Short: "Build an iOS simulator app in App Center and upload it to Waldo.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			if err := applyUploadFlagsFromEnv(cmd, "SIM_WALDO_UPLOAD_OPTIONS"); err != nil {
+				exitOnError(cmd, err)
+			}
+
+			exitOnError(
+				cmd,
+				waldo.NewAppCenterBuildAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	addUploadFlags(cmd.Flags(), &options.Upload)
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo appcenter build-and-upload [<upload-options>]
+
+Builds an iOS simulator app with xcodebuild, uploads it to Waldo, and then
+cancels the App Center build (if App Center credentials are provided).
+
+ENVIRONMENT:
+  SIM_XCODE_PROJECT       The path to the Xcode project or workspace.
+  SIM_XCODE_SCHEME        The Xcode scheme to build.
+  SIM_XCODE_CONFIGURATION The Xcode build configuration.
+  SIM_XCODE_APP_NAME      The name of the resulting app (e.g., “MyApp.app”).
+  SIM_XCODE_OPTIONS       Extra options to pass to xcodebuild (optional).
+  SIM_WALDO_UPLOAD_OPTIONS
+                          Extra upload options (optional; overridden by
+                          options on the command line).
+  SIM_APPCENTER_API_TOKEN
+  SIM_APPCENTER_APP_NAME
+  SIM_APPCENTER_OWNER_NAME
+                          The App Center API token, app name, and owner name
+                          used to cancel the App Center build (optional).
+
+OPTIONS:
+  Accepts the same options as “waldo upload” (except --help and --version).
+  The build is uploaded with Waldo Agent unless --direct_upload is given.
+`)
+
+	return cmd
+}
@@ -11,16 +11,18 @@ func NewAuthCommand() *cobra.Command {
 	options := &waldo.AuthOptions{}
 
 	cmd := &cobra.Command{
-		Use:   "auth [-v | --verbose] <api-token>",
+		Use:   "auth [--encrypt] [--profile <p>] [--token_file <f> | --token_stdin] [-v | --verbose] [<api-token>]",
 		Short: "Authenticate user access to Waldo.",
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			ioStreams := lib.NewIOStreams(
 				cmd.InOrStdin(),
 				cmd.OutOrStdout(),
 				cmd.ErrOrStderr())
 
-			options.APIToken = args[0]
+			if len(args) > 0 {
+				options.APIToken = args[0]
+			}
 
 			exitOnError(
 				cmd,
@@ -29,15 +31,29 @@ func NewAuthCommand() *cobra.Command {
 					ioStreams).Perform())
 		}}
 
+	cmd.Flags().BoolVar(&options.Encrypt, "encrypt", false, "Encrypt saved credentials with a passphrase.")
+	cmd.Flags().StringVar(&options.Profile, "profile", "", "The profile to save the credentials to (overrides WALDO_PROFILE).")
+	cmd.Flags().StringVar(&options.TokenFile, "token_file", "", "Read the API token from a file.")
+	cmd.Flags().BoolVar(&options.TokenStdin, "token_stdin", false, "Read the API token from standard input.")
 	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
 
 	cmd.SetUsageTemplate(`
-USAGE: waldo auth [-v | --verbose] <api-token>
+USAGE: waldo auth [--encrypt] [--profile <p>] [--token_file <f> | --token_stdin] [-v | --verbose] [<api-token>]
+
+If no API token is specified, it is prompted for (without echoing).
 
 ARGUMENTS:
-  <api-token>             The API token to authenticate with.
+  <api-token>             The API token to authenticate with (discouraged, as
+                          it may be exposed in shell history and process
+                          listings).
 
 OPTIONS:
+      --encrypt           Encrypt saved credentials with a passphrase (taken
+                          from WALDO_CREDENTIALS_PASSPHRASE or prompted for).
+      --profile <p>       The profile to save the credentials to (overrides
+                          WALDO_PROFILE; defaults to the current profile).
+      --token_file <f>    Read the API token from a file.
+      --token_stdin       Read the API token from standard input.
   -v, --verbose           Show extra verbiage.
 `)
 
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+)
+
+func NewCICommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ci <subcommand>",
+		Short: "Set up Waldo in a CI pipeline."}
+
+	cmd.AddCommand(fixup(newCIInitCommand()))
+
+	return cmd
+}
+
+//-----------------------------------------------------------------------------
+
+func newCIInitCommand() *cobra.Command {
+	options := &waldo.CIInitOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "init --provider <p> [--force] [--platform <p>] [--trigger]",
+		Short: "Generate a CI workflow that builds and uploads to Waldo.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			exitOnError(
+				cmd,
+				waldo.NewCIInitAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.Flags().BoolVar(&options.Force, "force", false, "Overwrite an existing workflow file (not bitrise.yml or .circleci/config.yml).")
+	cmd.Flags().StringVar(&options.Platform, "platform", "", "The platform to build (ios or android).")
+	cmd.Flags().StringVar(&options.Provider, "provider", "", "The CI provider (bitrise, circleci, github, or gitlab).")
+	cmd.Flags().BoolVar(&options.Trigger, "trigger", false, "Also trigger a Waldo run after uploading.")
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo ci init --provider <p> [--force] [--platform <p>] [--trigger]
+
+OPTIONS:
+      --force             Overwrite an existing workflow file. An existing
+                          bitrise.yml or .circleci/config.yml holds the whole
+                          pipeline, so it is never overwritten; the workflow is
+                          printed to merge into it by hand instead.
+      --platform <p>      The platform to build (“ios” or “android”). Defaults
+                          to iOS if the project has an iOS app.
+      --provider <p>      The CI provider (“bitrise”, “circleci”, “github”, or
+                          “gitlab”).
+      --trigger           Also trigger a Waldo run after uploading.
+`)
+
+	return cmd
+}
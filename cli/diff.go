@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+)
+
+func NewDiffBuildsCommand() *cobra.Command {
+	options := &waldo.DiffBuildsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff-builds [--format <f>] [-v | --verbose] <old-build-path> <new-build-path>",
+		Short: "Compare two build artifacts.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			options.OldBuildPath = args[0]
+			options.NewBuildPath = args[1]
+
+			exitOnError(
+				cmd,
+				waldo.NewDiffBuildsAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.Flags().StringVar(&options.Format, "format", "text", "The output format (text or json).")
+	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo diff-builds [--format <f>] [-v | --verbose] <old-build-path> <new-build-path>
+
+ARGUMENTS:
+  <old-build-path>        The path to the old (e.g., last good) build artifact.
+  <new-build-path>        The path to the new build artifact.
+
+OPTIONS:
+      --format <f>        The output format (“text” or “json”).
+  -v, --verbose           Show extra verbiage.
+`)
+
+	return cmd
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+)
+
+func NewLogoutCommand() *cobra.Command {
+	options := &waldo.LogoutOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "logout [--profile <p>] [-v | --verbose]",
+		Short: "Remove saved Waldo credentials.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			exitOnError(
+				cmd,
+				waldo.NewLogoutAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.Flags().StringVar(&options.Profile, "profile", "", "The profile to remove (overrides WALDO_PROFILE).")
+	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo logout [--profile <p>] [-v | --verbose]
+
+Removes the API token saved by “waldo auth” (along with its profile).
+
+OPTIONS:
+      --profile <p>       The profile to remove (overrides WALDO_PROFILE;
+                          defaults to the current profile).
+  -v, --verbose           Show extra verbiage.
+`)
+
+	return cmd
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+)
+
+func NewProfileCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "profile <subcommand>",
+		Short: "Manage named Waldo profiles."}
+
+	cmd.AddCommand(fixup(newProfileDeleteCommand()))
+	cmd.AddCommand(fixup(newProfileListCommand()))
+	cmd.AddCommand(fixup(newProfileShowCommand()))
+	cmd.AddCommand(fixup(newProfileUseCommand()))
+
+	return cmd
+}
+
+//-----------------------------------------------------------------------------
+
+func newProfileDeleteCommand() *cobra.Command {
+	options := &waldo.ProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a profile and its saved credentials.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			options.Name = args[0]
+
+			exitOnError(
+				cmd,
+				waldo.NewProfileDeleteAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo profile delete <name>
+
+ARGUMENTS:
+  <name>                  The name of the profile to delete.
+`)
+
+	return cmd
+}
+
+func newProfileListCommand() *cobra.Command {
+	options := &waldo.ProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles, marking the current one.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			exitOnError(
+				cmd,
+				waldo.NewProfileListAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo profile list
+`)
+
+	return cmd
+}
+
+func newProfileShowCommand() *cobra.Command {
+	options := &waldo.ProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "show [<name>]",
+		Short: "Show a profile.",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			if len(args) > 0 {
+				options.Name = args[0]
+			}
+
+			exitOnError(
+				cmd,
+				waldo.NewProfileShowAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo profile show [<name>]
+
+ARGUMENTS:
+  <name>                  The name of the profile to show (defaults to the
+                          current profile).
+`)
+
+	return cmd
+}
+
+func newProfileUseCommand() *cobra.Command {
+	options := &waldo.ProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Make a profile the current one.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			options.Name = args[0]
+
+			exitOnError(
+				cmd,
+				waldo.NewProfileUseAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo profile use <name>
+
+ARGUMENTS:
+  <name>                  The name of the profile to use when neither
+                          --profile nor WALDO_PROFILE is specified.
+`)
+
+	return cmd
+}
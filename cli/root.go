@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 
@@ -39,10 +40,16 @@ func NewRootCommand() *cobra.Command {
 	cmd.SetHelpTemplate(helpTemplate)
 	cmd.SetUsageTemplate(usageTemplate)
 
+	cmd.AddCommand(fixup(NewAppCenterCommand()))
 	cmd.AddCommand(fixup(NewAuthCommand()))
+	cmd.AddCommand(fixup(NewCICommand()))
+	cmd.AddCommand(fixup(NewDiffBuildsCommand()))
+	cmd.AddCommand(fixup(NewLogoutCommand()))
+	cmd.AddCommand(fixup(NewProfileCommand()))
 	cmd.AddCommand(fixup(NewTriggerCommand()))
 	cmd.AddCommand(fixup(NewUploadCommand()))
 	cmd.AddCommand(fixup(NewVersionCommand()))
+	cmd.AddCommand(fixup(NewWhoamiCommand()))
 
 	return fixup(cmd)
 }
@@ -62,6 +69,10 @@ func exitOnError(cmd *cobra.Command, err error) {
 			os.Exit(ee.ExitCode())
 		}
 
+		if errors.Is(err, lib.ErrInterrupted) {
+			os.Exit(130) // 128 + SIGINT
+		}
+
 		os.Exit(1)
 	}
 }
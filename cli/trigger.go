@@ -11,7 +11,7 @@ func NewTriggerCommand() *cobra.Command {
 	options := &waldo.TriggerOptions{}
 
 	cmd := &cobra.Command{
-		Use:   "trigger [--git_commit <c>] [--rule_name <r>] [--upload_token <t>] [-v | --verbose]",
+		Use:   "trigger [--dry_run] [--git_commit <c>] [--override_policy <r>] [--report <r>] [--rule_name <r>] [--upload_token <t>] [-v | --verbose]",
 		Short: "Trigger a run on Waldo.",
 		Args:  cobra.ExactArgs(0),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -27,20 +27,33 @@ func NewTriggerCommand() *cobra.Command {
 					ioStreams).Perform())
 		}}
 
+	cmd.Flags().BoolVar(&options.DryRun, "dry_run", false, "Show the resolved options without triggering a run.")
 	cmd.Flags().StringVar(&options.GitCommit, "git_commit", "", "The originating git commit hash.")
 	cmd.Flags().BoolVar(&options.LegacyHelp, "help", false, "Show available options and exit.")
+	cmd.Flags().StringVar(&options.OverridePolicy, "override_policy", "", "Proceed despite project policy violations, logging the reason.")
+	cmd.Flags().StringVar(&options.Report, "report", "", "Report the trigger result to a code host (github or gitlab).")
 	cmd.Flags().StringVar(&options.RuleName, "rule_name", "", "An optional rule name.")
 	cmd.Flags().StringVar(&options.UploadToken, "upload_token", "", "The upload token (overrides WALDO_UPLOAD_TOKEN).")
 	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
 	cmd.Flags().BoolVar(&options.LegacyVersion, "version", false, "Show version information and exit.")
 
 	cmd.SetUsageTemplate(`
-USAGE: waldo trigger [--git_commit <c>] [--rule_name <r>] [--upload_token <t>] [-v | --verbose]
+USAGE: waldo trigger [--dry_run] [--git_commit <c>] [--override_policy <r>] [--report <r>] [--rule_name <r>] [--upload_token <t>] [-v | --verbose]
 
 OPTIONS:
-      --git_commit <c>    The originating git commit hash.
-      --rule_name <r>     An optional rule name.
-      --upload_token <t>  The upload token (overrides WALDO_UPLOAD_TOKEN).
+      --dry_run           Show the resolved options without triggering a run.
+      --git_commit <c>    The originating git commit hash (inferred from the
+                          local git repository if omitted).
+      --override_policy <r>
+                          Proceed despite project policy violations, logging
+                          the given reason.
+      --report <r>        Report the trigger result to a code host (“github” or
+                          “gitlab”). Requires GITHUB_TOKEN or GITLAB_TOKEN.
+      --rule_name <r>     An optional rule name (overrides the trigger rule
+                          mapping in the project Waldo configuration).
+      --upload_token <t>  The upload token (overrides WALDO_UPLOAD_TOKEN, or
+                          the contents of the file named by
+                          WALDO_UPLOAD_TOKEN_FILE).
   -v, --verbose           Show extra verbiage.
 `)
 
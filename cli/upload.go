@@ -5,13 +5,14 @@ import (
 	"github.com/waldoapp/waldo-go-cli/waldo"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 func NewUploadCommand() *cobra.Command {
 	options := &waldo.UploadOptions{}
 
 	cmd := &cobra.Command{
-		Use:   "upload [--app_id <a>] [--git_branch <b>] [--git_commit <c>] [--upload_token <t>] [--variant_name <n>] [-v | --verbose ] <build-path>",
+		Use:   "upload [--app_id <a>] [--direct_upload] [--dry_run] [--from_eas <e> [--eas_platform <p>]] [--from_url <u> [--from_url_header <h>]... [--from_url_token <t>]] [--git_branch <b>] [--git_commit <c>] [--meta <k=v>]... [--override_policy <r>] [--profile <p>] [--release_notes <r> [--release_notes_limit <n>] [--release_notes_template <t>]] [--report <r>] [--target <t>] [--upload_token <t>] [--variant_name <n>] [-v | --verbose ] <build-path>",
 		Short: "Upload a build artifact to Waldo.",
 		Args:  cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
@@ -31,29 +32,105 @@ func NewUploadCommand() *cobra.Command {
 					ioStreams).Perform())
 		}}
 
-	cmd.Flags().StringVar(&options.AppID, "app_id", "", "An app ID (if using an API token).")
-	cmd.Flags().StringVar(&options.GitBranch, "git_branch", "", "The originating git commit branch name.")
-	cmd.Flags().StringVar(&options.GitCommit, "git_commit", "", "The originating git commit hash.")
+	addUploadFlags(cmd.Flags(), options)
+
 	cmd.Flags().BoolVar(&options.LegacyHelp, "help", false, "Show available options and exit.")
-	cmd.Flags().StringVar(&options.UploadToken, "upload_token", "", "The upload token (overrides WALDO_UPLOAD_TOKEN).")
-	cmd.Flags().StringVar(&options.VariantName, "variant_name", "", "An optional variant name.")
-	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
 	cmd.Flags().BoolVar(&options.LegacyVersion, "version", false, "Show version information and exit.")
 
 	cmd.SetUsageTemplate(`
-USAGE: waldo upload [--app_id <a>] [--git_branch <b>] [--git_commit <c>] [--upload_token <t>] [--variant_name <n>] [-v | --verbose ] <build-path>
+USAGE: waldo upload [--app_id <a>] [--direct_upload] [--dry_run] [--from_eas <e> [--eas_platform <p>]] [--from_url <u> [--from_url_header <h>]... [--from_url_token <t>]] [--git_branch <b>] [--git_commit <c>] [--meta <k=v>]... [--override_policy <r>] [--profile <p>] [--release_notes <r> [--release_notes_limit <n>] [--release_notes_template <t>]] [--report <r>] [--target <t>] [--upload_token <t>] [--variant_name <n>] [-v | --verbose ] <build-path>
 
 ARGUMENTS:
-  <build-path>            The path to the build artifact to upload.
+  <build-path>            The path to the build artifact to upload (not allowed
+                          with --from_eas or --from_url; optional with
+                          --target).
 
 OPTIONS:
       --app_id <a>        An app ID (if not in CI mode).
-      --git_branch <b>    The originating git commit branch name.
-      --git_commit <c>    The originating git commit hash.
-      --upload_token <t>  The upload token (overrides WALDO_UPLOAD_TOKEN).
+      --direct_upload     Upload directly to storage (bypasses Waldo Agent).
+      --dry_run           Show the resolved options without uploading (a
+                          local build is still unpacked and checked against
+                          the project policy).
+      --eas_platform <p>  The EAS build platform (“ios” or “android”) to
+                          restrict --from_eas to.
+      --from_eas <e>      An EAS build ID to upload. Specify “latest” for the
+                          most recent finished simulator/emulator EAS build,
+                          or “local” for the most recent artifact of a local
+                          EAS build (“eas build --local”) in the current
+                          directory (build-*.tar.gz or build-*.apk).
+      --from_url <u>      A URL to download the build artifact from (a
+                          .tar.gz is extracted as it is downloaded; anything
+                          else goes to a temporary file that is removed
+                          afterwards).
+      --from_url_header <h>
+                          An HTTP header (“Name: Value”) to send with the
+                          download (repeatable).
+      --from_url_token <t>
+                          A bearer token for the download (overrides
+                          WALDO_FROM_URL_TOKEN).
+      --git_branch <b>    The originating git commit branch name (inferred
+                          from the local git repository if omitted).
+      --git_commit <c>    The originating git commit hash (inferred from the
+                          local git repository if omitted).
+      --meta <k=v>        A custom metadata item to attach to the upload
+                          (repeatable; overrides the project Waldo
+                          configuration).
+      --override_policy <r>
+                          Proceed despite project policy violations, logging
+                          the given reason. All violations are reported
+                          together, except that, with --from_eas or
+                          --from_url, the build version is only checked once
+                          the build has been downloaded.
+      --profile <p>       The profile whose API token to use as the upload
+                          token (overrides WALDO_UPLOAD_TOKEN and
+                          WALDO_PROFILE).
+      --release_notes <r> Release notes to attach to the upload as the build
+                          description. Specify “auto” to generate them from
+                          the git commit subjects since the previous upload
+                          of this app/variant from this machine.
+      --release_notes_limit <n>
+                          The maximum number of commits to include in
+                          generated release notes (default 20).
+      --release_notes_template <t>
+                          A Go template to format generated release notes
+                          (overrides the project Waldo configuration).
+      --report <r>        Report the upload result to a code host (“github” or
+                          “gitlab”). Requires GITHUB_TOKEN or GITLAB_TOKEN.
+      --target <t>        A named target from the project Waldo configuration
+                          supplying the build path, app ID, variant name,
+                          and platform (overridden by explicit options).
+      --upload_token <t>  The upload token (overrides WALDO_UPLOAD_TOKEN, or
+                          the contents of the file named by
+                          WALDO_UPLOAD_TOKEN_FILE).
       --variant_name <n>  An optional variant name.
   -v, --verbose           Show extra verbiage.
 `)
 
 	return cmd
 }
+
+//-----------------------------------------------------------------------------
+
+func addUploadFlags(flags *pflag.FlagSet, options *waldo.UploadOptions) {
+	flags.StringVar(&options.AppID, "app_id", "", "An app ID (if using an API token).")
+	flags.BoolVar(&options.DirectUpload, "direct_upload", false, "Upload directly to storage (bypasses Waldo Agent).")
+	flags.BoolVar(&options.DryRun, "dry_run", false, "Show the resolved options without uploading.")
+	flags.StringVar(&options.EASPlatform, "eas_platform", "", "The EAS build platform (ios or android).")
+	flags.StringVar(&options.FromEAS, "from_eas", "", "An EAS build ID (or “latest” or “local”) to upload.")
+	flags.StringVar(&options.FromURL, "from_url", "", "A URL to download the build artifact from.")
+	flags.StringArrayVar(&options.FromURLHeaders, "from_url_header", nil, "An HTTP header to send with the download (repeatable).")
+	flags.StringVar(&options.FromURLToken, "from_url_token", "", "A bearer token for the download (overrides WALDO_FROM_URL_TOKEN).")
+	flags.StringVar(&options.GitBranch, "git_branch", "", "The originating git commit branch name.")
+	flags.StringVar(&options.GitCommit, "git_commit", "", "The originating git commit hash.")
+	flags.StringArrayVar(&options.Metadata, "meta", nil, "A custom metadata item (repeatable).")
+	flags.StringVar(&options.OverridePolicy, "override_policy", "", "Proceed despite project policy violations, logging the reason.")
+	flags.StringVar(&options.Profile, "profile", "", "The profile whose API token to use (overrides WALDO_PROFILE).")
+	flags.StringVar(&options.ReleaseNotes, "release_notes", "", "Release notes text (or “auto” to generate from git history).")
+	flags.IntVar(&options.ReleaseNotesLimit, "release_notes_limit", 0, "The maximum number of commits in generated release notes.")
+	flags.StringVar(&options.ReleaseNotesTemplate, "release_notes_template", "", "A Go template to format generated release notes.")
+	flags.StringVar(&options.Report, "report", "", "Report the upload result to a code host (github or gitlab).")
+	flags.StringVar(&options.Target, "target", "", "A named target from the project Waldo configuration.")
+	flags.StringVar(&options.UploadToken, "upload_token", "", "The upload token (overrides WALDO_UPLOAD_TOKEN).")
+	flags.StringVar(&options.VariantName, "variant_name", "", "An optional variant name.")
+	flags.BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo"
+
+	"github.com/spf13/cobra"
+)
+
+func NewWhoamiCommand() *cobra.Command {
+	options := &waldo.WhoamiOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "whoami [--json] [--profile <p>] [-v | --verbose]",
+		Short: "Show the authenticated Waldo user.",
+		Args:  cobra.ExactArgs(0),
+		Run: func(cmd *cobra.Command, args []string) {
+			ioStreams := lib.NewIOStreams(
+				cmd.InOrStdin(),
+				cmd.OutOrStdout(),
+				cmd.ErrOrStderr())
+
+			exitOnError(
+				cmd,
+				waldo.NewWhoamiAction(
+					options,
+					ioStreams).Perform())
+		}}
+
+	cmd.Flags().BoolVar(&options.JSON, "json", false, "Show the result as JSON.")
+	cmd.Flags().StringVar(&options.Profile, "profile", "", "The profile to use (overrides WALDO_PROFILE).")
+	cmd.Flags().BoolVarP(&options.Verbose, "verbose", "v", false, "Show extra verbiage.")
+
+	cmd.SetUsageTemplate(`
+USAGE: waldo whoami [--json] [--profile <p>] [-v | --verbose]
+
+Shows the user authenticated by the API token of the given profile or, if not
+specified, WALDO_UPLOAD_TOKEN or the API token saved by “waldo auth” in the
+current profile, along with where the token came from.
+
+OPTIONS:
+      --json              Show the result as JSON.
+      --profile <p>       The profile to use (overrides WALDO_UPLOAD_TOKEN and
+                          WALDO_PROFILE).
+  -v, --verbose           Show extra verbiage.
+`)
+
+	return cmd
+}
@@ -0,0 +1,421 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type ExtractLimits struct {
+	MaxEntries int   // zero means no limit
+	MaxSize    int64 // total uncompressed bytes; zero means no limit
+}
+
+//-----------------------------------------------------------------------------
+
+func ExtractTarGz(tgzPath, dirPath string, limits *ExtractLimits) error {
+	file, err := os.Open(tgzPath)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	return ExtractTarGzFrom(file, dirPath, limits)
+}
+
+// ExtractTarGzFrom extracts a gzipped tarball as it is read (for example, as
+// it is downloaded), so that the tarball itself need not be saved first.
+func ExtractTarGzFrom(r io.Reader, dirPath string, limits *ExtractLimits) error {
+	gzr, err := gzip.NewReader(r)
+
+	if err != nil {
+		return err
+	}
+
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	ex := newExtractor(dirPath, limits)
+
+	for {
+		hdr, err := tr.Next()
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		path, err := ex.prepareEntry(hdr.Name)
+
+		if err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = ex.extractDirectory(path)
+
+		case tar.TypeReg:
+			err = ex.extractFile(tr, path, hdr.FileInfo().Mode().Perm())
+
+		case tar.TypeSymlink:
+			err = ex.extractSymlink(hdr.Linkname, path)
+
+		default:
+			continue // ignore hard links, devices, etc.
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func ExtractZip(zipPath, dirPath string, limits *ExtractLimits) error {
+	zr, err := zip.OpenReader(zipPath)
+
+	if err != nil {
+		return err
+	}
+
+	defer zr.Close()
+
+	ex := newExtractor(dirPath, limits)
+
+	for _, zf := range zr.File {
+		path, err := ex.prepareEntry(zf.Name)
+
+		if err != nil {
+			return err
+		}
+
+		mode := zf.Mode()
+
+		switch {
+		case mode.IsDir():
+			err = ex.extractDirectory(path)
+
+		case mode&fs.ModeSymlink != 0:
+			err = ex.extractZipSymlink(zf, path)
+
+		case mode.IsRegular():
+			err = ex.extractZipFile(zf, path)
+
+		default:
+			continue // ignore devices, etc.
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func ZipDirectory(dirPath, zipPath string) error {
+	file, err := os.OpenFile(zipPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+
+	basePath := filepath.Dir(dirPath)
+
+	err = filepath.WalkDir(dirPath, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		return addZipEntry(zw, path, MakeRelative(path, basePath), de)
+	})
+
+	if err != nil {
+		zw.Close()
+
+		return err
+	}
+
+	return zw.Close()
+}
+
+//-----------------------------------------------------------------------------
+
+func addZipEntry(zw *zip.Writer, path, name string, de fs.DirEntry) error {
+	fi, err := de.Info()
+
+	if err != nil {
+		return err
+	}
+
+	hdr, err := zip.FileInfoHeader(fi)
+
+	if err != nil {
+		return err
+	}
+
+	hdr.Name = filepath.ToSlash(name)
+
+	switch {
+	case fi.IsDir():
+		hdr.Name += "/"
+		hdr.Method = zip.Store
+
+		_, err = zw.CreateHeader(hdr)
+
+		return err
+
+	case fi.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+
+		if err != nil {
+			return err
+		}
+
+		hdr.Method = zip.Store
+
+		w, err := zw.CreateHeader(hdr)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(w, target)
+
+		return err
+
+	default:
+		hdr.Method = zip.Deflate
+
+		w, err := zw.CreateHeader(hdr)
+
+		if err != nil {
+			return err
+		}
+
+		src, err := os.Open(path)
+
+		if err != nil {
+			return err
+		}
+
+		defer src.Close()
+
+		_, err = io.Copy(w, src)
+
+		return err
+	}
+}
+
+func isPathSeparator(r rune) bool {
+	return r == '/' || r == '\\'
+}
+
+func isWithin(path, dirPath string) bool {
+	relPath, err := filepath.Rel(filepath.Clean(dirPath), filepath.Clean(path))
+
+	if err != nil {
+		return false
+	}
+
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+//-----------------------------------------------------------------------------
+
+type extractor struct {
+	dirPath string
+	entries int
+	limits  *ExtractLimits
+	size    int64
+}
+
+//-----------------------------------------------------------------------------
+
+func newExtractor(dirPath string, limits *ExtractLimits) *extractor {
+	if limits == nil {
+		limits = &ExtractLimits{}
+	}
+
+	return &extractor{
+		dirPath: dirPath,
+		limits:  limits}
+}
+
+//-----------------------------------------------------------------------------
+
+// checkParents refuses an entry whose path goes through a symlink already
+// extracted, as creating anything there would follow it.
+func (ex *extractor) checkParents(path string) error {
+	relPath, err := filepath.Rel(ex.dirPath, filepath.Dir(path))
+
+	if err != nil || relPath == "." {
+		return err
+	}
+
+	parentPath := ex.dirPath
+
+	for _, component := range strings.Split(relPath, string(filepath.Separator)) {
+		parentPath = filepath.Join(parentPath, component)
+
+		fi, err := os.Lstat(parentPath)
+
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // nothing further down exists yet
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			return fmt.Errorf("Archive entry goes through symlink: %q", MakeRelative(path, ex.dirPath))
+		}
+	}
+
+	return nil
+}
+
+func (ex *extractor) extractDirectory(path string) error {
+	if err := ex.checkParents(path); err != nil {
+		return err
+	}
+
+	return os.MkdirAll(path, 0755)
+}
+
+func (ex *extractor) extractFile(r io.Reader, path string, perm fs.FileMode) error {
+	if err := ex.checkParents(path); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm|0600)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	if ex.limits.MaxSize <= 0 {
+		_, err = io.Copy(file, r)
+
+		return err
+	}
+
+	remaining := ex.limits.MaxSize - ex.size
+
+	written, err := io.Copy(file, io.LimitReader(r, remaining+1))
+
+	ex.size += written
+
+	if err != nil {
+		return err
+	}
+
+	if written > remaining {
+		return fmt.Errorf("Archive exceeds maximum extracted size of %d bytes", ex.limits.MaxSize)
+	}
+
+	return nil
+}
+
+// extractSymlink creates a symlink only if its target is relative and never
+// goes up a directory. Checking where the target would resolve to is not
+// enough, as that can change with symlinks extracted later (for example,
+// after “a -> .”, “b -> a/../x” resolves outside of the destination), but a
+// symlink that can only point down from its own directory cannot escape the
+// destination, however it is combined with others.
+func (ex *extractor) extractSymlink(target, path string) error {
+	if len(target) == 0 || isPathSeparator(rune(target[0])) || filepath.IsAbs(target) || len(filepath.VolumeName(target)) > 0 {
+		return fmt.Errorf("Archive symlink escapes destination: %q", target)
+	}
+
+	for _, component := range strings.FieldsFunc(target, isPathSeparator) {
+		if component == ".." {
+			return fmt.Errorf("Archive symlink escapes destination: %q", target)
+		}
+	}
+
+	if err := ex.checkParents(path); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return os.Symlink(target, path)
+}
+
+func (ex *extractor) extractZipFile(zf *zip.File, path string) error {
+	rc, err := zf.Open()
+
+	if err != nil {
+		return err
+	}
+
+	defer rc.Close()
+
+	return ex.extractFile(rc, path, zf.Mode().Perm())
+}
+
+func (ex *extractor) extractZipSymlink(zf *zip.File, path string) error {
+	rc, err := zf.Open()
+
+	if err != nil {
+		return err
+	}
+
+	defer rc.Close()
+
+	target, err := io.ReadAll(io.LimitReader(rc, 4096))
+
+	if err != nil {
+		return err
+	}
+
+	return ex.extractSymlink(string(target), path)
+}
+
+func (ex *extractor) prepareEntry(name string) (string, error) {
+	ex.entries++
+
+	if ex.limits.MaxEntries > 0 && ex.entries > ex.limits.MaxEntries {
+		return "", fmt.Errorf("Archive exceeds maximum number of entries (%d)", ex.limits.MaxEntries)
+	}
+
+	name = filepath.FromSlash(name)
+
+	if filepath.IsAbs(name) || len(filepath.VolumeName(name)) > 0 || strings.HasPrefix(name, string(filepath.Separator)) {
+		return "", fmt.Errorf("Archive entry has absolute path: %q", name)
+	}
+
+	path := filepath.Join(ex.dirPath, name)
+
+	if !isWithin(path, ex.dirPath) {
+		return "", fmt.Errorf("Archive entry escapes destination: %q", name)
+	}
+
+	return path, nil
+}
@@ -0,0 +1,227 @@
+package lib
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// testEntry is an archive entry: a directory (name ending in “/”), a symlink
+// (non-empty link), or else a regular file.
+type testEntry struct {
+	name     string
+	contents string
+	link     string
+}
+
+func writeTestTarGz(t *testing.T, entries []testEntry) string {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.contents)), Typeflag: tar.TypeReg}
+
+		switch {
+		case strings.HasSuffix(entry.name, "/"):
+			hdr.Mode, hdr.Size, hdr.Typeflag = 0755, 0, tar.TypeDir
+
+		case len(entry.link) > 0:
+			hdr.Linkname, hdr.Size, hdr.Typeflag = entry.link, 0, tar.TypeSymlink
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		tw.Write([]byte(entry.contents))
+	}
+
+	tw.Close()
+	gw.Close()
+
+	path := filepath.Join(t.TempDir(), "test.tar.gz")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func writeTestZip(t *testing.T, entries []testEntry) string {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{Name: entry.name, Method: zip.Store}
+		contents := entry.contents
+
+		switch {
+		case strings.HasSuffix(entry.name, "/"):
+			hdr.SetMode(fs.ModeDir | 0755)
+
+		case len(entry.link) > 0:
+			hdr.SetMode(fs.ModeSymlink | 0777)
+
+			contents = entry.link
+
+		default:
+			hdr.SetMode(0644)
+		}
+
+		w, err := zw.CreateHeader(hdr)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(contents))
+	}
+
+	zw.Close()
+
+	path := filepath.Join(t.TempDir(), "test.zip")
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestExtract(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+
+	tests := []struct {
+		name    string
+		entries []testEntry
+		limits  *ExtractLimits
+		wantErr string
+	}{
+		{
+			name: "app with framework symlinks",
+			entries: []testEntry{
+				{name: "Sample.app/"},
+				{name: "Sample.app/Info.plist", contents: "<plist/>"},
+				{name: "Sample.app/Frameworks/F.framework/Versions/A/F", contents: "binary"},
+				{name: "Sample.app/Frameworks/F.framework/Versions/Current", link: "A"},
+				{name: "Sample.app/Frameworks/F.framework/F", link: "Versions/Current/F"}}},
+		{
+			name:    "parent directory entry",
+			entries: []testEntry{{name: "../evil", contents: "x"}},
+			wantErr: "escapes destination"},
+		{
+			name:    "nested parent directory entry",
+			entries: []testEntry{{name: "Sample.app/../../evil", contents: "x"}},
+			wantErr: "escapes destination"},
+		{
+			name:    "absolute entry",
+			entries: []testEntry{{name: "/tmp/evil", contents: "x"}},
+			wantErr: "absolute path"},
+		{
+			name:    "symlink up and out",
+			entries: []testEntry{{name: "link", link: "../outside"}},
+			wantErr: "symlink escapes destination"},
+		{
+			name:    "absolute symlink",
+			entries: []testEntry{{name: "link", link: "/etc"}},
+			wantErr: "symlink escapes destination"},
+		{
+			name: "chained symlinks",
+			entries: []testEntry{
+				{name: "d/x", link: ".."},
+				{name: "d/x/z", link: ".."},
+				{name: "z/evil", contents: "x"}},
+			wantErr: "symlink escapes destination"},
+		{
+			name: "symlink through symlink",
+			entries: []testEntry{
+				{name: "a", link: "."},
+				{name: "b", link: "a/../x"}},
+			wantErr: "symlink escapes destination"},
+		{
+			name: "file through symlink",
+			entries: []testEntry{
+				{name: "sub/"},
+				{name: "link", link: "sub"},
+				{name: "link/evil", contents: "x"}},
+			wantErr: "goes through symlink"},
+		{
+			name: "too many entries",
+			entries: []testEntry{
+				{name: "a", contents: "x"},
+				{name: "b", contents: "x"},
+				{name: "c", contents: "x"}},
+			limits:  &ExtractLimits{MaxEntries: 2},
+			wantErr: "maximum number of entries"},
+		{
+			name: "too large",
+			entries: []testEntry{
+				{name: "a", contents: "12345"},
+				{name: "b", contents: "67890"}},
+			limits:  &ExtractLimits{MaxSize: 8},
+			wantErr: "maximum extracted size"},
+	}
+
+	formats := map[string]struct {
+		write   func(t *testing.T, entries []testEntry) string
+		extract func(path, dirPath string, limits *ExtractLimits) error
+	}{
+		"tar.gz": {writeTestTarGz, ExtractTarGz},
+		"zip":    {writeTestZip, ExtractZip},
+	}
+
+	for format, ff := range formats {
+		for _, tt := range tests {
+			t.Run(format+"/"+tt.name, func(t *testing.T) {
+				rootPath := t.TempDir()
+				dirPath := filepath.Join(rootPath, "dest")
+
+				if err := os.Mkdir(dirPath, 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				err := ff.extract(ff.write(t, tt.entries), dirPath, tt.limits)
+
+				if len(tt.wantErr) > 0 {
+					if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+						t.Fatalf("extract error = %v, want %q", err, tt.wantErr)
+					}
+				} else if err != nil {
+					t.Fatalf("extract error = %v", err)
+				}
+
+				//
+				// Nothing is ever written outside of the destination:
+				//
+				outside, _ := os.ReadDir(rootPath)
+
+				if len(outside) != 1 {
+					t.Errorf("written outside destination: %v", outside)
+				}
+
+				if len(tt.wantErr) > 0 {
+					return
+				}
+
+				contents, err := os.ReadFile(filepath.Join(dirPath, "Sample.app", "Frameworks", "F.framework", "F"))
+
+				if err != nil || string(contents) != "binary" {
+					t.Errorf("framework binary through symlinks = %q, %v", contents, err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package ci
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+type Provider string
+
+const (
+	ProviderAppCenter      Provider = "App Center"
+	ProviderAzurePipelines Provider = "Azure Pipelines"
+	ProviderBitbucket      Provider = "Bitbucket Pipelines"
+	ProviderBitrise        Provider = "Bitrise"
+	ProviderBuildkite      Provider = "Buildkite"
+	ProviderCircleCI       Provider = "CircleCI"
+	ProviderCodemagic      Provider = "Codemagic"
+	ProviderGitHubActions  Provider = "GitHub Actions"
+	ProviderGitLabCI       Provider = "GitLab CI"
+	ProviderJenkins        Provider = "Jenkins"
+	ProviderXcodeCloud     Provider = "Xcode Cloud"
+)
+
+type Info struct {
+	Actor       string
+	BaseBranch  string // pull request target branch
+	Branch      string // pull request source branch, if any
+	BuildNumber string
+	BuildURL    string
+	Commit      string
+	HeadCommit  string // pull request head commit, if known
+	PullRequest string
+	Provider    Provider
+}
+
+//-----------------------------------------------------------------------------
+
+type detector struct {
+	provider Provider
+	matches  func(env lib.Environment) bool
+	extract  func(env lib.Environment) *Info
+}
+
+// Order matters: App Center builds run on Azure Pipelines agents (and set
+// TF_BUILD), so App Center must be checked first.
+var detectors = []*detector{
+	{ProviderAppCenter, hasVar("APPCENTER_BUILD_ID"), extractAppCenter},
+	{ProviderAzurePipelines, hasVar("TF_BUILD"), extractAzurePipelines},
+	{ProviderBitbucket, hasVar("BITBUCKET_BUILD_NUMBER"), extractBitbucket},
+	{ProviderBitrise, hasVar("BITRISE_IO"), extractBitrise},
+	{ProviderBuildkite, hasVar("BUILDKITE"), extractBuildkite},
+	{ProviderCircleCI, hasVar("CIRCLECI"), extractCircleCI},
+	{ProviderCodemagic, hasVar("CM_BUILD_ID"), extractCodemagic},
+	{ProviderGitHubActions, hasVar("GITHUB_ACTIONS"), extractGitHubActions},
+	{ProviderGitLabCI, hasVar("GITLAB_CI"), extractGitLabCI},
+	{ProviderJenkins, hasVar("JENKINS_URL"), extractJenkins},
+	{ProviderXcodeCloud, hasVar("CI_XCODE_PROJECT"), extractXcodeCloud}}
+
+//-----------------------------------------------------------------------------
+
+func Detect(env lib.Environment) *Info {
+	for _, d := range detectors {
+		if d.matches(env) {
+			info := d.extract(env)
+
+			info.Provider = d.provider
+
+			return info
+		}
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (info *Info) Metadata() map[string]string {
+	metadata := make(map[string]string)
+
+	add := func(key, value string) {
+		if len(value) > 0 {
+			metadata["ci."+key] = value
+		}
+	}
+
+	add("actor", info.Actor)
+	add("base_branch", info.BaseBranch)
+	add("build_number", info.BuildNumber)
+	add("build_url", info.BuildURL)
+	add("provider", string(info.Provider))
+	add("pull_request", info.PullRequest)
+
+	return metadata
+}
+
+//-----------------------------------------------------------------------------
+
+func extractAppCenter(env lib.Environment) *Info {
+	return &Info{
+		Branch:      env["APPCENTER_BRANCH"],
+		BuildNumber: env["APPCENTER_BUILD_ID"],
+		Commit:      env["BUILD_SOURCEVERSION"]}
+}
+
+func extractAzurePipelines(env lib.Environment) *Info {
+	var buildURL string
+
+	if collectionURI, buildID := env["SYSTEM_COLLECTIONURI"], env["BUILD_BUILDID"]; len(collectionURI) > 0 && len(buildID) > 0 {
+		buildURL = strings.TrimSuffix(collectionURI, "/") + "/" + env["SYSTEM_TEAMPROJECT"] + "/_build/results?buildId=" + buildID
+	}
+
+	pullRequest := env["SYSTEM_PULLREQUEST_PULLREQUESTNUMBER"]
+
+	if len(pullRequest) == 0 {
+		pullRequest = env["SYSTEM_PULLREQUEST_PULLREQUESTID"]
+	}
+
+	return &Info{
+		Actor:       env["BUILD_REQUESTEDFOR"],
+		Branch:      trimRef(firstOf(env, "SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCH")),
+		BuildNumber: env["BUILD_BUILDNUMBER"],
+		BuildURL:    buildURL,
+		Commit:      env["BUILD_SOURCEVERSION"],
+		PullRequest: pullRequest}
+}
+
+func extractBitbucket(env lib.Environment) *Info {
+	var buildURL, headCommit string
+
+	if origin := env["BITBUCKET_GIT_HTTP_ORIGIN"]; len(origin) > 0 {
+		buildURL = origin + "/addon/pipelines/home#!/results/" + env["BITBUCKET_BUILD_NUMBER"]
+	}
+
+	pullRequest := env["BITBUCKET_PR_ID"]
+
+	if len(pullRequest) > 0 {
+		headCommit = env["BITBUCKET_COMMIT"]
+	}
+
+	return &Info{
+		Actor:       env["BITBUCKET_STEP_TRIGGERER_UUID"],
+		BaseBranch:  env["BITBUCKET_PR_DESTINATION_BRANCH"],
+		Branch:      env["BITBUCKET_BRANCH"],
+		BuildNumber: env["BITBUCKET_BUILD_NUMBER"],
+		BuildURL:    buildURL,
+		Commit:      env["BITBUCKET_COMMIT"],
+		HeadCommit:  headCommit,
+		PullRequest: pullRequest}
+}
+
+func extractBitrise(env lib.Environment) *Info {
+	return &Info{
+		Actor:       env["GIT_CLONE_COMMIT_AUTHOR_NAME"],
+		Branch:      env["BITRISE_GIT_BRANCH"],
+		BuildNumber: env["BITRISE_BUILD_NUMBER"],
+		BuildURL:    env["BITRISE_BUILD_URL"],
+		Commit:      firstOf(env, "BITRISE_GIT_COMMIT", "GIT_CLONE_COMMIT_HASH"),
+		PullRequest: env["BITRISE_PULL_REQUEST"]}
+}
+
+func extractBuildkite(env lib.Environment) *Info {
+	pullRequest := env["BUILDKITE_PULL_REQUEST"]
+
+	if pullRequest == "false" {
+		pullRequest = ""
+	}
+
+	return &Info{
+		Actor:       env["BUILDKITE_BUILD_CREATOR"],
+		Branch:      env["BUILDKITE_BRANCH"],
+		BuildNumber: env["BUILDKITE_BUILD_NUMBER"],
+		BuildURL:    env["BUILDKITE_BUILD_URL"],
+		Commit:      env["BUILDKITE_COMMIT"],
+		PullRequest: pullRequest}
+}
+
+func extractCircleCI(env lib.Environment) *Info {
+	pullRequest := env["CIRCLE_PR_NUMBER"]
+
+	if prURL := env["CIRCLE_PULL_REQUEST"]; len(pullRequest) == 0 && len(prURL) > 0 {
+		pullRequest = path.Base(prURL)
+	}
+
+	return &Info{
+		Actor:       env["CIRCLE_USERNAME"],
+		Branch:      env["CIRCLE_BRANCH"],
+		BuildNumber: env["CIRCLE_BUILD_NUM"],
+		BuildURL:    env["CIRCLE_BUILD_URL"],
+		Commit:      env["CIRCLE_SHA1"],
+		PullRequest: pullRequest}
+}
+
+func extractCodemagic(env lib.Environment) *Info {
+	var buildURL string
+
+	if projectID := env["CM_PROJECT_ID"]; len(projectID) > 0 {
+		buildURL = "https://codemagic.io/app/" + projectID + "/build/" + env["CM_BUILD_ID"]
+	}
+
+	return &Info{
+		Branch:      firstOf(env, "CM_PULL_REQUEST_SOURCE", "CM_BRANCH"),
+		BuildNumber: env["BUILD_NUMBER"],
+		BuildURL:    buildURL,
+		Commit:      env["CM_COMMIT"],
+		PullRequest: env["CM_PULL_REQUEST_NUMBER"]}
+}
+
+func extractGitHubActions(env lib.Environment) *Info {
+	var buildURL, pullRequest string
+
+	if repo, runID := env["GITHUB_REPOSITORY"], env["GITHUB_RUN_ID"]; len(repo) > 0 && len(runID) > 0 {
+		serverURL := env["GITHUB_SERVER_URL"]
+
+		if len(serverURL) == 0 {
+			serverURL = "https://github.com"
+		}
+
+		buildURL = serverURL + "/" + repo + "/actions/runs/" + runID
+	}
+
+	ref := env["GITHUB_REF"]
+
+	if strings.HasPrefix(ref, "refs/pull/") {
+		pullRequest = strings.SplitN(strings.TrimPrefix(ref, "refs/pull/"), "/", 2)[0]
+	}
+
+	branch := env["GITHUB_HEAD_REF"]
+
+	if len(branch) == 0 && strings.HasPrefix(ref, "refs/heads/") {
+		branch = trimRef(ref)
+	}
+
+	var headCommit string
+
+	//
+	// On pull_request events, GITHUB_SHA is a synthetic merge commit; the real
+	// head commit is only available from the event payload:
+	//
+	if len(pullRequest) > 0 {
+		headCommit = readGitHubHeadCommit(env["GITHUB_EVENT_PATH"])
+	}
+
+	return &Info{
+		Actor:       env["GITHUB_ACTOR"],
+		BaseBranch:  env["GITHUB_BASE_REF"],
+		Branch:      branch,
+		BuildNumber: env["GITHUB_RUN_NUMBER"],
+		BuildURL:    buildURL,
+		Commit:      env["GITHUB_SHA"],
+		HeadCommit:  headCommit,
+		PullRequest: pullRequest}
+}
+
+func extractGitLabCI(env lib.Environment) *Info {
+	var headCommit string
+
+	//
+	// In merged results pipelines, CI_COMMIT_SHA is a synthetic merge commit;
+	// CI_MERGE_REQUEST_SOURCE_BRANCH_SHA is only set in that case:
+	//
+	if len(env["CI_MERGE_REQUEST_IID"]) > 0 {
+		headCommit = firstOf(env, "CI_MERGE_REQUEST_SOURCE_BRANCH_SHA", "CI_COMMIT_SHA")
+	}
+
+	return &Info{
+		Actor:       env["GITLAB_USER_LOGIN"],
+		BaseBranch:  env["CI_MERGE_REQUEST_TARGET_BRANCH_NAME"],
+		Branch:      firstOf(env, "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH"),
+		BuildNumber: env["CI_PIPELINE_IID"],
+		BuildURL:    firstOf(env, "CI_JOB_URL", "CI_PIPELINE_URL"),
+		Commit:      env["CI_COMMIT_SHA"],
+		HeadCommit:  headCommit,
+		PullRequest: env["CI_MERGE_REQUEST_IID"]}
+}
+
+func extractJenkins(env lib.Environment) *Info {
+	branch := env["CHANGE_BRANCH"]
+
+	if len(branch) == 0 {
+		branch = strings.TrimPrefix(firstOf(env, "BRANCH_NAME", "GIT_BRANCH"), "origin/")
+	}
+
+	return &Info{
+		Actor:       firstOf(env, "CHANGE_AUTHOR", "BUILD_USER_ID"),
+		Branch:      branch,
+		BuildNumber: env["BUILD_NUMBER"],
+		BuildURL:    env["BUILD_URL"],
+		Commit:      env["GIT_COMMIT"],
+		PullRequest: env["CHANGE_ID"]}
+}
+
+func extractXcodeCloud(env lib.Environment) *Info {
+	return &Info{
+		Branch:      firstOf(env, "CI_PULL_REQUEST_SOURCE_BRANCH", "CI_BRANCH"),
+		BuildNumber: env["CI_BUILD_NUMBER"],
+		BuildURL:    env["CI_BUILD_URL"],
+		Commit:      env["CI_COMMIT"],
+		PullRequest: env["CI_PULL_REQUEST_NUMBER"]}
+}
+
+//-----------------------------------------------------------------------------
+
+func firstOf(env lib.Environment, keys ...string) string {
+	for _, key := range keys {
+		if value := env[key]; len(value) > 0 {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func readGitHubHeadCommit(eventPath string) string {
+	if len(eventPath) == 0 {
+		return ""
+	}
+
+	data, err := os.ReadFile(eventPath)
+
+	if err != nil {
+		return ""
+	}
+
+	var event struct {
+		PullRequest struct {
+			Head struct {
+				SHA string `json:"sha"`
+			} `json:"head"`
+		} `json:"pull_request"`
+	}
+
+	if err := json.Unmarshal(data, &event); err != nil {
+		return ""
+	}
+
+	return event.PullRequest.Head.SHA
+}
+
+func hasVar(key string) func(lib.Environment) bool {
+	return func(env lib.Environment) bool {
+		return len(env[key]) > 0
+	}
+}
+
+func trimRef(ref string) string {
+	for _, prefix := range []string{"refs/heads/", "refs/tags/"} {
+		if strings.HasPrefix(ref, prefix) {
+			return strings.TrimPrefix(ref, prefix)
+		}
+	}
+
+	return ref
+}
@@ -0,0 +1,252 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+func writeGitHubEvent(t *testing.T, headSHA string) string {
+	eventPath := filepath.Join(t.TempDir(), "event.json")
+
+	if err := os.WriteFile(eventPath, []byte(`{"pull_request":{"head":{"sha":"`+headSHA+`"}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return eventPath
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name            string
+		env             lib.Environment
+		wantProvider    Provider
+		wantBranch      string
+		wantCommit      string
+		wantPullRequest string
+		wantHeadCommit  string
+	}{
+		{
+			name: "App Center (before Azure Pipelines)",
+			env: lib.Environment{
+				"APPCENTER_BUILD_ID":  "17",
+				"APPCENTER_BRANCH":    "main",
+				"BUILD_SOURCEVERSION": "aaa111",
+				"TF_BUILD":            "True"},
+			wantProvider: ProviderAppCenter,
+			wantBranch:   "main",
+			wantCommit:   "aaa111"},
+		{
+			name: "Azure Pipelines pull request",
+			env: lib.Environment{
+				"TF_BUILD":                             "True",
+				"BUILD_SOURCEBRANCH":                   "refs/pull/12/merge",
+				"SYSTEM_PULLREQUEST_SOURCEBRANCH":      "refs/heads/feature/login",
+				"SYSTEM_PULLREQUEST_PULLREQUESTNUMBER": "12",
+				"BUILD_SOURCEVERSION":                  "bbb222"},
+			wantProvider:    ProviderAzurePipelines,
+			wantBranch:      "feature/login",
+			wantCommit:      "bbb222",
+			wantPullRequest: "12"},
+		{
+			name: "Bitbucket Pipelines push",
+			env: lib.Environment{
+				"BITBUCKET_BUILD_NUMBER": "5",
+				"BITBUCKET_BRANCH":       "main",
+				"BITBUCKET_COMMIT":       "ccc333"},
+			wantProvider: ProviderBitbucket,
+			wantBranch:   "main",
+			wantCommit:   "ccc333"},
+		{
+			name: "Bitbucket Pipelines pull request",
+			env: lib.Environment{
+				"BITBUCKET_BUILD_NUMBER":          "6",
+				"BITBUCKET_BRANCH":                "feature/login",
+				"BITBUCKET_COMMIT":                "ccc444",
+				"BITBUCKET_PR_ID":                 "34",
+				"BITBUCKET_PR_DESTINATION_BRANCH": "main"},
+			wantProvider:    ProviderBitbucket,
+			wantBranch:      "feature/login",
+			wantCommit:      "ccc444",
+			wantPullRequest: "34",
+			wantHeadCommit:  "ccc444"},
+		{
+			name: "Bitrise",
+			env: lib.Environment{
+				"BITRISE_IO":            "true",
+				"BITRISE_GIT_BRANCH":    "develop",
+				"GIT_CLONE_COMMIT_HASH": "ddd444",
+				"BITRISE_PULL_REQUEST":  "56"},
+			wantProvider:    ProviderBitrise,
+			wantBranch:      "develop",
+			wantCommit:      "ddd444",
+			wantPullRequest: "56"},
+		{
+			name: "Buildkite",
+			env: lib.Environment{
+				"BUILDKITE":              "true",
+				"BUILDKITE_BRANCH":       "main",
+				"BUILDKITE_COMMIT":       "eee555",
+				"BUILDKITE_PULL_REQUEST": "false"},
+			wantProvider: ProviderBuildkite,
+			wantBranch:   "main",
+			wantCommit:   "eee555"},
+		{
+			name: "CircleCI",
+			env: lib.Environment{
+				"CIRCLECI":            "true",
+				"CIRCLE_BRANCH":       "feature/login",
+				"CIRCLE_SHA1":         "fff666",
+				"CIRCLE_PULL_REQUEST": "https://github.com/acme/app/pull/78"},
+			wantProvider:    ProviderCircleCI,
+			wantBranch:      "feature/login",
+			wantCommit:      "fff666",
+			wantPullRequest: "78"},
+		{
+			name: "Codemagic",
+			env: lib.Environment{
+				"CM_BUILD_ID":            "b-1",
+				"CM_BRANCH":              "main",
+				"CM_PULL_REQUEST_SOURCE": "feature/login",
+				"CM_PULL_REQUEST_NUMBER": "90",
+				"CM_COMMIT":              "abc777"},
+			wantProvider:    ProviderCodemagic,
+			wantBranch:      "feature/login",
+			wantCommit:      "abc777",
+			wantPullRequest: "90"},
+		{
+			name: "GitHub Actions push",
+			env: lib.Environment{
+				"GITHUB_ACTIONS": "true",
+				"GITHUB_REF":     "refs/heads/main",
+				"GITHUB_SHA":     "abc888"},
+			wantProvider: ProviderGitHubActions,
+			wantBranch:   "main",
+			wantCommit:   "abc888"},
+		{
+			name: "GitHub Actions pull request",
+			env: lib.Environment{
+				"GITHUB_ACTIONS":    "true",
+				"GITHUB_REF":        "refs/pull/91/merge",
+				"GITHUB_HEAD_REF":   "feature/login",
+				"GITHUB_BASE_REF":   "main",
+				"GITHUB_SHA":        "merge999",
+				"GITHUB_EVENT_PATH": "{event}"},
+			wantProvider:    ProviderGitHubActions,
+			wantBranch:      "feature/login",
+			wantCommit:      "merge999",
+			wantPullRequest: "91",
+			wantHeadCommit:  "head999"},
+		{
+			name: "GitLab CI push",
+			env: lib.Environment{
+				"GITLAB_CI":        "true",
+				"CI_COMMIT_BRANCH": "main",
+				"CI_COMMIT_SHA":    "abc000"},
+			wantProvider: ProviderGitLabCI,
+			wantBranch:   "main",
+			wantCommit:   "abc000"},
+		{
+			name: "GitLab CI merged results pipeline",
+			env: lib.Environment{
+				"GITLAB_CI":                           "true",
+				"CI_COMMIT_SHA":                       "merge111",
+				"CI_MERGE_REQUEST_IID":                "23",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME": "feature/login",
+				"CI_MERGE_REQUEST_SOURCE_BRANCH_SHA":  "head111"},
+			wantProvider:    ProviderGitLabCI,
+			wantBranch:      "feature/login",
+			wantCommit:      "merge111",
+			wantPullRequest: "23",
+			wantHeadCommit:  "head111"},
+		{
+			name: "Jenkins",
+			env: lib.Environment{
+				"JENKINS_URL": "https://jenkins.example.com/",
+				"GIT_BRANCH":  "origin/main",
+				"GIT_COMMIT":  "abc222"},
+			wantProvider: ProviderJenkins,
+			wantBranch:   "main",
+			wantCommit:   "abc222"},
+		{
+			name: "Xcode Cloud",
+			env: lib.Environment{
+				"CI_XCODE_PROJECT":              "Sample.xcodeproj",
+				"CI_BRANCH":                     "main",
+				"CI_PULL_REQUEST_SOURCE_BRANCH": "feature/login",
+				"CI_PULL_REQUEST_NUMBER":        "45",
+				"CI_COMMIT":                     "abc333"},
+			wantProvider:    ProviderXcodeCloud,
+			wantBranch:      "feature/login",
+			wantCommit:      "abc333",
+			wantPullRequest: "45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env["GITHUB_EVENT_PATH"] == "{event}" {
+				tt.env["GITHUB_EVENT_PATH"] = writeGitHubEvent(t, tt.wantHeadCommit)
+			}
+
+			info := Detect(tt.env)
+
+			if info == nil {
+				t.Fatalf("Detect() = nil, want %v", tt.wantProvider)
+			}
+
+			if info.Provider != tt.wantProvider {
+				t.Errorf("Provider = %q, want %q", info.Provider, tt.wantProvider)
+			}
+
+			if info.Branch != tt.wantBranch {
+				t.Errorf("Branch = %q, want %q", info.Branch, tt.wantBranch)
+			}
+
+			if info.Commit != tt.wantCommit {
+				t.Errorf("Commit = %q, want %q", info.Commit, tt.wantCommit)
+			}
+
+			if info.PullRequest != tt.wantPullRequest {
+				t.Errorf("PullRequest = %q, want %q", info.PullRequest, tt.wantPullRequest)
+			}
+
+			if info.HeadCommit != tt.wantHeadCommit {
+				t.Errorf("HeadCommit = %q, want %q", info.HeadCommit, tt.wantHeadCommit)
+			}
+		})
+	}
+}
+
+func TestDetectNoCI(t *testing.T) {
+	if info := Detect(lib.Environment{"HOME": "/home/tester", "CI": "true"}); info != nil {
+		t.Errorf("Detect() = %+v, want nil", info)
+	}
+}
+
+func TestInfoMetadata(t *testing.T) {
+	info := &Info{
+		BaseBranch:  "main",
+		BuildNumber: "7",
+		Provider:    ProviderGitHubActions,
+		PullRequest: "91"}
+
+	metadata := info.Metadata()
+
+	want := map[string]string{
+		"ci.base_branch":  "main",
+		"ci.build_number": "7",
+		"ci.provider":     "GitHub Actions",
+		"ci.pull_request": "91"}
+
+	if len(metadata) != len(want) {
+		t.Errorf("Metadata() = %v, want %v", metadata, want)
+	}
+
+	for key, value := range want {
+		if metadata[key] != value {
+			t.Errorf("Metadata()[%q] = %q, want %q", key, metadata[key], value)
+		}
+	}
+}
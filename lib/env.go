@@ -1,6 +1,7 @@
 package lib
 
 import (
+	"fmt"
 	"os"
 	"strings"
 )
@@ -30,6 +31,41 @@ func CurrentEnvironment() Environment {
 	return env
 }
 
+// GetenvSecret returns the value of the named environment variable or, if it
+// is not set, the contents of the file named by the same variable with a
+// “_FILE” suffix (the convention for Docker and Kubernetes secrets).
+func GetenvSecret(name string) (string, error) {
+	if value := os.Getenv(name); len(value) > 0 {
+		return value, nil
+	}
+
+	path := os.Getenv(name + "_FILE")
+
+	if len(path) == 0 {
+		return "", nil
+	}
+
+	value, err := ReadSecretFile(path)
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to read %v, error: %v", name+"_FILE", err)
+	}
+
+	return value, nil
+}
+
+// ReadSecretFile returns the contents of the file, less any surrounding
+// whitespace (such as a trailing newline).
+func ReadSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
 //-----------------------------------------------------------------------------
 
 func (env Environment) Flatten() []string {
@@ -0,0 +1,163 @@
+package lib
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+const (
+	lockRetryInterval = 100 * time.Millisecond
+	staleLockAge      = 2 * time.Minute
+)
+
+//-----------------------------------------------------------------------------
+
+// LockFile acquires an advisory lock on the file at the given path by
+// exclusively creating a sibling “.lock” file, waiting up to the given timeout
+// for any other process holding the lock to release it. A lock file older
+// than a couple of minutes is assumed to be left behind by a process that
+// crashed and is removed (by one waiting process only). The returned function
+// releases the lock.
+func LockFile(path string, timeout time.Duration) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(timeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+
+			file.Close()
+
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(lockPath); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			if removeStaleLock(lockPath) {
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			if IsRegularFile(lockPath + ".break") {
+				return nil, fmt.Errorf("Timed out waiting for lock file %q -- remove it and %q if no other process is running", lockPath, lockPath+".break")
+			}
+
+			return nil, fmt.Errorf("Timed out waiting for lock file %q -- remove it if no other process is running", lockPath)
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// WriteFileAtomic writes data to the file at the given path such that the
+// file is never left truncated or partially written: the data is written to a
+// temporary file in the same directory, flushed to disk, and then renamed
+// over the original file.
+func WriteFileAtomic(path string, data []byte, perm fs.FileMode) error {
+	dirPath := filepath.Dir(path)
+
+	file, err := os.CreateTemp(dirPath, "."+filepath.Base(path)+".*.tmp")
+
+	if err != nil {
+		return err
+	}
+
+	tmpPath := file.Name()
+
+	if err := writeAndSync(file, data, perm); err != nil {
+		os.Remove(tmpPath)
+
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+
+		return err
+	}
+
+	syncDirectory(dirPath)
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// removeStaleLock removes the lock file at the given path if it is still
+// stale, reporting whether it is gone. Only the process that exclusively
+// creates the sibling “.break” file may remove it; otherwise two processes
+// could both find it stale, and the second could remove the lock file just
+// created by the first, leaving both believing that they hold the lock.
+func removeStaleLock(lockPath string) bool {
+	breakPath := lockPath + ".break"
+
+	file, err := os.OpenFile(breakPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+
+	if err != nil {
+		return false // another process is removing it
+	}
+
+	file.Close()
+
+	defer os.Remove(breakPath)
+
+	fi, err := os.Stat(lockPath)
+
+	if err != nil {
+		return errors.Is(err, fs.ErrNotExist)
+	}
+
+	if time.Since(fi.ModTime()) <= staleLockAge {
+		return false // replaced by another process in the meantime
+	}
+
+	return os.Remove(lockPath) == nil
+}
+
+func syncDirectory(path string) {
+	//
+	// Directories cannot be opened for syncing on Windows (where a rename is
+	// durable anyway):
+	//
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	if dir, err := os.Open(path); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+}
+
+func writeAndSync(file *os.File, data []byte, perm fs.FileMode) error {
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Chmod(perm); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,87 @@
+package lib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockFileStale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yml")
+	lockPath := path + ".lock"
+
+	if err := os.WriteFile(lockPath, []byte("0\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := time.Now().Add(-2 * staleLockAge)
+
+	if err := os.Chtimes(lockPath, stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	//
+	// All waiters find the lock stale, yet only one at a time may hold it:
+	//
+	var (
+		holders    atomic.Int32
+		overlapped atomic.Bool
+		wg         sync.WaitGroup
+	)
+
+	for idx := 0; idx < 8; idx++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			unlock, err := LockFile(path, 10*time.Second)
+
+			if err != nil {
+				t.Error(err)
+
+				return
+			}
+
+			if holders.Add(1) > 1 {
+				overlapped.Store(true)
+			}
+
+			time.Sleep(10 * time.Millisecond)
+
+			holders.Add(-1)
+
+			unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if overlapped.Load() {
+		t.Errorf("lock held by more than one waiter at once")
+	}
+
+	if IsRegularFile(lockPath) || IsRegularFile(lockPath+".break") {
+		t.Errorf("lock files left behind")
+	}
+}
+
+func TestLockFileTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profile.yml")
+
+	unlock, err := LockFile(path, time.Second)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer unlock()
+
+	if _, err := LockFile(path, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "Timed out") {
+		t.Errorf("LockFile() error = %v, want timeout", err)
+	}
+}
@@ -0,0 +1,240 @@
+package git
+
+import (
+	"errors"
+	"net/url"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+type Info struct {
+	AuthorEmail string
+	AuthorName  string
+	Branch      string
+	Commit      string
+	Detached    bool
+	Dirty       bool
+	Message     string
+	RemoteURL   string
+	Shallow     bool
+	TopLevel    string
+}
+
+//-----------------------------------------------------------------------------
+
+func Inspect(rev string) (*Info, error) {
+	if !IsAvailable() {
+		return nil, errors.New("git not found")
+	}
+
+	topLevel, err := Run("rev-parse", "--show-toplevel")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rev) == 0 {
+		rev = "HEAD"
+	}
+
+	info := &Info{TopLevel: topLevel}
+
+	//
+	// The commit may be absent from a shallow clone (for example, the head of
+	// a pull request when only the merge commit was fetched); keep it as-is:
+	//
+	if info.Commit, err = ResolveCommit(rev); err != nil {
+		info.Commit = rev
+	} else if output, err := Run("log", "-1", "--format=%an%x1f%ae%x1f%B", info.Commit); err == nil {
+		if fields := strings.SplitN(output, "\x1f", 3); len(fields) == 3 {
+			info.AuthorName = fields[0]
+			info.AuthorEmail = fields[1]
+			info.Message = strings.TrimSpace(fields[2])
+		}
+	}
+
+	head, _ := ResolveCommit("HEAD")
+
+	atHead := len(head) > 0 && info.Commit == head
+
+	info.Branch, info.Detached = findBranch(info.Commit, atHead)
+
+	//
+	// Uncommitted changes only matter if they sit on top of the commit:
+	//
+	info.Dirty = atHead && isDirty()
+	info.RemoteURL = findRemoteURL()
+	info.Shallow = isShallow()
+
+	return info, nil
+}
+
+func IsAvailable() bool {
+	_, err := exec.LookPath("git")
+
+	return err == nil
+}
+
+// LastCommitForPath returns the most recent commit reachable from rev that
+// touched any of the given paths, or an empty string if there is none.
+func LastCommitForPath(rev string, paths ...string) (string, error) {
+	args := append([]string{"log", "-1", "--format=%H", rev, "--"}, paths...)
+
+	return Run(args...)
+}
+
+func ResolveCommit(rev string) (string, error) {
+	return Run("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+}
+
+func Run(args ...string) (string, error) {
+	task := lib.NewTask("git", args...)
+
+	task.Env = lib.CurrentEnvironment()
+
+	stdout, stderr, err := task.Run()
+
+	if err != nil {
+		if len(stderr) > 0 {
+			return "", errors.New(stderr)
+		}
+
+		return "", err
+	}
+
+	return stdout, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func findBranch(commit string, atHead bool) (string, bool) {
+	current, err := Run("symbolic-ref", "--quiet", "--short", "HEAD")
+
+	detached := err != nil || len(current) == 0
+
+	if atHead && !detached {
+		return current, false
+	}
+
+	//
+	// Either HEAD is detached (typical in CI checkouts) or the commit is not
+	// HEAD. Look for branches that point at the commit or, failing that, for
+	// branches that contain it:
+	//
+	candidates := findBranchRefs("--points-at", commit)
+
+	if len(candidates) == 0 && !atHead {
+		candidates = findBranchRefs("--contains", commit)
+	}
+
+	return pickBranch(candidates, current, findDefaultBranch()), detached
+}
+
+// findBranchRefs returns the names of the local and remote-tracking branches
+// matching the given for-each-ref filter (with any remote name stripped), in
+// that order and without duplicates.
+func findBranchRefs(filter, commit string) []string {
+	var branches []string
+
+	for _, pattern := range []string{"refs/heads", "refs/remotes"} {
+		output, err := Run("for-each-ref", filter, commit, "--format=%(refname:lstrip=2)", pattern)
+
+		if err != nil {
+			continue
+		}
+
+		for _, ref := range strings.Split(output, "\n") {
+			if len(ref) == 0 || strings.HasSuffix(ref, "/HEAD") {
+				continue
+			}
+
+			if pattern == "refs/remotes" {
+				if _, branch, found := strings.Cut(ref, "/"); found {
+					ref = branch
+				}
+			}
+
+			if !slices.Contains(branches, ref) {
+				branches = append(branches, ref)
+			}
+		}
+	}
+
+	return branches
+}
+
+func findDefaultBranch() string {
+	ref, err := Run("symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(ref, "origin/")
+}
+
+func findRemoteURL() string {
+	remoteURL, err := Run("config", "--get", "remote.origin.url")
+
+	if err != nil || len(remoteURL) == 0 {
+		remotes, err := Run("remote")
+
+		if err != nil || len(remotes) == 0 {
+			return ""
+		}
+
+		remote, _, _ := strings.Cut(remotes, "\n")
+
+		if remoteURL, err = Run("config", "--get", "remote."+remote+".url"); err != nil {
+			return ""
+		}
+	}
+
+	return sanitizeRemoteURL(remoteURL)
+}
+
+func isDirty() bool {
+	output, err := Run("status", "--porcelain", "--untracked-files=no")
+
+	return err == nil && len(output) > 0
+}
+
+func isShallow() bool {
+	output, err := Run("rev-parse", "--is-shallow-repository")
+
+	return err == nil && output == "true"
+}
+
+// pickBranch prefers the current branch, then the default branch of origin,
+// among the candidate branches. Any other branch is only picked if it is the
+// sole candidate, rather than guessing between several.
+func pickBranch(candidates []string, current, defaultBranch string) string {
+	for _, preferred := range []string{current, defaultBranch} {
+		if len(preferred) > 0 && slices.Contains(candidates, preferred) {
+			return preferred
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	return ""
+}
+
+func sanitizeRemoteURL(remoteURL string) string {
+	//
+	// Strip any embedded credentials (e.g., https://x-access-token:…@host/…)
+	// so they never end up in verbose output or upload metadata:
+	//
+	if u, err := url.Parse(remoteURL); err == nil && u.User != nil && len(u.Scheme) > 0 {
+		u.User = nil
+
+		return u.String()
+	}
+
+	return remoteURL
+}
@@ -0,0 +1,136 @@
+package git
+
+import (
+	"os"
+	"testing"
+)
+
+// setupTestRepo creates a repository with two commits on main, a feature
+// branch with one commit off the first, and an uncommitted change on main; it
+// then makes it the working directory.
+func setupTestRepo(t *testing.T) (string, string, string) {
+	if !IsAvailable() {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+
+	wd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	commit := func(message string) string {
+		run(t, "commit", "--allow-empty", "--quiet", "-m", message)
+
+		sha, _ := ResolveCommit("HEAD")
+
+		return sha
+	}
+
+	run(t, "init", "--quiet", "--initial-branch=main")
+	run(t, "config", "user.name", "Tester")
+	run(t, "config", "user.email", "tester@example.com")
+
+	first := commit("First")
+
+	run(t, "checkout", "--quiet", "-b", "feature")
+
+	feature := commit("Feature")
+
+	run(t, "checkout", "--quiet", "main")
+
+	second := commit("Second")
+
+	if err := os.WriteFile("file.txt", []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	run(t, "add", "file.txt")
+
+	return first, second, feature
+}
+
+func run(t *testing.T, args ...string) {
+	if _, err := Run(args...); err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	first, second, feature := setupTestRepo(t)
+
+	tests := []struct {
+		name         string
+		rev          string
+		wantCommit   string
+		wantBranch   string
+		wantDirty    bool
+		wantDetached bool
+	}{
+		{"HEAD", "", second, "main", true, false},
+		{"older commit on current branch", first, first, "main", false, false},
+		{"tip of other branch", feature, feature, "feature", false, false},
+		{"unknown commit", "0123456789abcdef0123456789abcdef01234567", "0123456789abcdef0123456789abcdef01234567", "", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := Inspect(tt.rev)
+
+			if err != nil {
+				t.Fatalf("Inspect() error = %v", err)
+			}
+
+			if info.Commit != tt.wantCommit || info.Branch != tt.wantBranch || info.Dirty != tt.wantDirty || info.Detached != tt.wantDetached {
+				t.Errorf("Inspect() = %+v", info)
+			}
+		})
+	}
+
+	//
+	// With HEAD detached, an older commit belonging to several branches is
+	// only attributed to one of them if there is a reason to prefer it:
+	//
+	run(t, "checkout", "--quiet", "--detach", second)
+	run(t, "branch", "--quiet", "release", second)
+	run(t, "branch", "--quiet", "--delete", "--force", "main")
+
+	if info, _ := Inspect(first); info.Branch != "" || info.Dirty || !info.Detached {
+		t.Errorf("Inspect() of ambiguous commit = %+v", info)
+	}
+}
+
+func TestPickBranch(t *testing.T) {
+	tests := []struct {
+		name          string
+		candidates    []string
+		current       string
+		defaultBranch string
+		want          string
+	}{
+		{"current branch", []string{"feature", "main"}, "main", "develop", "main"},
+		{"default branch", []string{"feature", "main"}, "", "main", "main"},
+		{"sole candidate", []string{"feature"}, "", "main", "feature"},
+		{"ambiguous", []string{"feature", "release"}, "", "main", ""},
+		{"none", nil, "main", "main", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pickBranch(tt.candidates, tt.current, tt.defaultBranch); got != tt.want {
+				t.Errorf("pickBranch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
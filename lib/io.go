@@ -2,12 +2,20 @@ package lib
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+
+	"golang.org/x/term"
 )
 
+// ErrInterrupted is returned by PromptReader.ReadSecret when interrupted.
+var ErrInterrupted = errors.New("Interrupted")
+
 type IOStreams struct {
 	inReader  io.Reader
 	outWriter io.Writer
@@ -55,9 +63,20 @@ func (ios *IOStreams) PrintErrf(format string, a ...any) (n int, err error) {
 	return ios.PrintErr(fmt.Sprintf(format, a...))
 }
 
+func (ios *IOStreams) ReadAll() ([]byte, error) {
+	return io.ReadAll(ios.inReader)
+}
+
+// TeeOutput returns I/O streams that also copy standard output to the given
+// writer.
+func (ios *IOStreams) TeeOutput(w io.Writer) *IOStreams {
+	return NewIOStreams(ios.inReader, io.MultiWriter(ios.outWriter, w), ios.errWriter)
+}
+
 //-----------------------------------------------------------------------------
 
 type PromptReader struct {
+	inFile    *os.File // nil if not a file
 	inReader  *bufio.Reader
 	outWriter io.Writer
 }
@@ -65,7 +84,10 @@ type PromptReader struct {
 //-----------------------------------------------------------------------------
 
 func (ios *IOStreams) PromptReader() *PromptReader {
+	inFile, _ := ios.inReader.(*os.File)
+
 	return &PromptReader{
+		inFile:    inFile,
 		inReader:  bufio.NewReader(ios.inReader),
 		outWriter: ios.outWriter}
 }
@@ -140,6 +162,75 @@ func (pr *PromptReader) ReadYN(prompt string) bool {
 	return false
 }
 
+// IsTerminal reports whether the file is a terminal.
+func IsTerminal(file *os.File) bool {
+	return term.IsTerminal(int(file.Fd()))
+}
+
+// ReadSecret prompts for a value without echoing it. It fails if the input is
+// not a terminal, and returns ErrInterrupted if interrupted (Ctrl-C) -- with
+// echo turned back on -- so that the caller can stop.
+func (pr *PromptReader) ReadSecret(prompt string) (string, error) {
+	if pr.inFile == nil || !IsTerminal(pr.inFile) {
+		return "", errors.New("input is not a terminal")
+	}
+
+	fd := int(pr.inFile.Fd())
+
+	state, err := term.GetState(fd)
+
+	if err != nil {
+		return "", errors.New("input is not a terminal")
+	}
+
+	//
+	// Echo is turned off while reading; an interrupt (Ctrl-C) would otherwise
+	// exit without turning it back on, so it is caught instead:
+	//
+	interrupts := make(chan os.Signal, 1)
+
+	signal.Notify(interrupts, os.Interrupt)
+
+	defer signal.Stop(interrupts)
+
+	type readResult struct {
+		input []byte
+		err   error
+	}
+
+	results := make(chan readResult, 1)
+
+	fmt.Fprintf(pr.outWriter, "\n%v: ", prompt)
+
+	go func() {
+		input, err := term.ReadPassword(fd)
+
+		results <- readResult{input, err}
+	}()
+
+	select {
+	case <-interrupts:
+		//
+		// The read cannot be cancelled, so it is abandoned (along with any
+		// input it gets later) and echo is turned back on here instead:
+		//
+		term.Restore(fd, state)
+
+		fmt.Fprintln(pr.outWriter)
+
+		return "", ErrInterrupted
+
+	case result := <-results:
+		fmt.Fprintln(pr.outWriter) // the newline was not echoed
+
+		if result.err != nil {
+			return "", result.err
+		}
+
+		return strings.TrimRight(string(result.input), "\r\n"), nil
+	}
+}
+
 //-----------------------------------------------------------------------------
 
 func (pr *PromptReader) formatChoosePrompt(prompt string, minChoice, maxChoice int) string {
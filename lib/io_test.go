@@ -0,0 +1,52 @@
+package lib
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPromptReaderReadSecretNotTerminal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "input.txt")
+
+	if err := os.WriteFile(path, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer file.Close()
+
+	devNull, err := os.Open(os.DevNull)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer devNull.Close()
+
+	tests := []struct {
+		name  string
+		input io.Reader
+	}{
+		{"reader", strings.NewReader("secret\n")},
+		{"regular file", file},
+		{"null device", devNull},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioStreams := NewIOStreams(tt.input, io.Discard, io.Discard)
+
+			if _, err := ioStreams.PromptReader().ReadSecret("Secret"); err == nil || err.Error() != "input is not a terminal" {
+				t.Errorf("ReadSecret() error = %v, want not a terminal", err)
+			}
+		})
+	}
+}
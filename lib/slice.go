@@ -1,5 +1,10 @@
 package lib
 
+import (
+	"cmp"
+	"slices"
+)
+
 func CompactMap[T any, U any](slice []T, fn func(T) (U, bool)) []U {
 	var result []U
 
@@ -21,3 +26,15 @@ func Map[T any, U any](slice []T, fn func(T) U) []U {
 
 	return result
 }
+
+func SortedKeys[K cmp.Ordered, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
@@ -0,0 +1,115 @@
+package waldo
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+// agentUsage is the usage text that the downloaded Waldo Agent prints for one
+// of its commands. Rather than guessing from the agent version, it is searched
+// for the options and environment variables that the agent actually accepts.
+type agentUsage string
+
+//-----------------------------------------------------------------------------
+
+func probeAgentUsage(path, command string) agentUsage {
+	task := lib.NewTask(path, command, "--help")
+
+	task.Env = lib.CurrentEnvironment()
+
+	//
+	// Some agent versions exit with a non-zero status after printing usage, and
+	// some print it to stderr, so keep whatever was printed:
+	//
+	stdout, stderr, _ := task.Run()
+
+	return agentUsage(stdout + "\n" + stderr)
+}
+
+//-----------------------------------------------------------------------------
+
+func (au agentUsage) accepts(name string) bool {
+	return strings.Contains(string(au), name)
+}
+
+// readsUploadToken reports whether the upload token can be handed to this
+// Waldo Agent through its environment rather than its command line (where any
+// other process can see it). If its usage does not mention WALDO_UPLOAD_TOKEN,
+// the agent gets the upload token on the command line instead, with a warning.
+func (au agentUsage) readsUploadToken(ioStreams *lib.IOStreams) bool {
+	if au.accepts("WALDO_UPLOAD_TOKEN") {
+		return true
+	}
+
+	ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Waldo Agent does not read the upload token from its environment -- passing it on the command line instead"))
+
+	return false
+}
+
+//-----------------------------------------------------------------------------
+
+// agentOutputScanner scans what Waldo Agent prints, line by line, for the ID
+// and URL of the build it uploaded (or the run it triggered), so that they can
+// be reported.
+type agentOutputScanner struct {
+	id      string
+	idRE    *regexp.Regexp
+	partial []byte
+	url     string
+}
+
+// The agent quotes the ID after the noun (e.g., Build “abc123”); links point
+// at the Waldo web app rather than at the API:
+var agentURLRE = regexp.MustCompile(`https://app\.waldo\.com/[^\s"'“”‘’<>()\[\]]+`)
+
+func newAgentOutputScanner(noun string) *agentOutputScanner {
+	return &agentOutputScanner{
+		idRE: regexp.MustCompile(`(?i)\b` + noun + `\s+["'“‘]([^"'”’\s]+)["'”’]`)}
+}
+
+//-----------------------------------------------------------------------------
+
+func (aos *agentOutputScanner) Flush() {
+	if len(aos.partial) > 0 {
+		aos.scanLine(string(aos.partial))
+
+		aos.partial = nil
+	}
+}
+
+func (aos *agentOutputScanner) Write(p []byte) (int, error) {
+	aos.partial = append(aos.partial, p...)
+
+	for {
+		idx := bytes.IndexByte(aos.partial, '\n')
+
+		if idx < 0 {
+			break
+		}
+
+		aos.scanLine(string(aos.partial[:idx]))
+
+		aos.partial = aos.partial[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (aos *agentOutputScanner) scanLine(line string) {
+	if len(aos.id) == 0 {
+		if match := aos.idRE.FindStringSubmatch(line); match != nil {
+			aos.id = match[1]
+		}
+	}
+
+	if len(aos.url) == 0 {
+		aos.url = strings.TrimRight(agentURLRE.FindString(line), ".,;:")
+	}
+}
@@ -0,0 +1,235 @@
+package waldo
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// writeTestAgent writes a stand-in for Waldo Agent that prints the given usage
+// text (to stderr, exiting with a non-zero status, like some agent versions).
+func writeTestAgent(t *testing.T, usage string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in agent is a shell script")
+	}
+
+	path := filepath.Join(t.TempDir(), "waldo-agent")
+	script := "#!/bin/sh\ncat >&2 <<'EOF'\n" + usage + "\nEOF\nexit 2\n"
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestProbeAgentUsage(t *testing.T) {
+	usage := probeAgentUsage(writeTestAgent(t, "  --upload_token <value>  Upload token (overrides WALDO_UPLOAD_TOKEN)"), "upload")
+
+	if !usage.accepts("WALDO_UPLOAD_TOKEN") {
+		t.Errorf("accepts(WALDO_UPLOAD_TOKEN) = false, usage = %q", usage)
+	}
+
+	if usage.accepts("WALDO_UPLOAD_METADATA") {
+		t.Errorf("accepts(WALDO_UPLOAD_METADATA) = true")
+	}
+
+	if usage := probeAgentUsage(filepath.Join(t.TempDir(), "missing"), "upload"); usage.accepts("WALDO_UPLOAD_TOKEN") {
+		t.Errorf("accepts() of missing agent = true")
+	}
+}
+
+func TestAgentUploadToken(t *testing.T) {
+	const token = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name        string
+		usage       string
+		wantEnv     bool
+		wantWarning bool
+	}{
+		{"read from environment", "  --upload_token <value>  Upload token (overrides WALDO_UPLOAD_TOKEN)", true, false},
+		{"command line only", "  --upload_token <value>  Upload token", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, &stderr)
+			usage := probeAgentUsage(writeTestAgent(t, tt.usage), "upload")
+
+			ua := NewUploadAction(&UploadOptions{}, ioStreams)
+
+			ua.uploadToken = token
+			ua.tokenInEnvironment = usage.readsUploadToken(ioStreams)
+
+			ta := NewTriggerAction(&TriggerOptions{}, ioStreams)
+
+			ta.uploadToken = token
+			ta.tokenInEnvironment = usage.readsUploadToken(ioStreams)
+
+			for verb, got := range map[string]struct {
+				args []string
+				env  map[string]string
+			}{
+				"upload":  {ua.makeAgentArgs(), ua.enrichEnvironment()},
+				"trigger": {ta.makeAgentArgs(), ta.enrichEnvironment()},
+			} {
+				if gotEnv := got.env["WALDO_UPLOAD_TOKEN"] == token; gotEnv != tt.wantEnv {
+					t.Errorf("%v: WALDO_UPLOAD_TOKEN set = %v, want %v", verb, gotEnv, tt.wantEnv)
+				}
+
+				if gotArg := strings.Contains(strings.Join(got.args, " "), "--upload_token "+token); gotArg == tt.wantEnv {
+					t.Errorf("%v: args = %q", verb, got.args)
+				}
+			}
+
+			if gotWarning := strings.Contains(stderr.String(), "passing it on the command line"); gotWarning != tt.wantWarning {
+				t.Errorf("warning = %q, want warning %v", stderr.String(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestUploadActionAgentMetadata(t *testing.T) {
+	tests := []struct {
+		name        string
+		usage       string
+		wantEnv     bool
+		wantWarning bool
+	}{
+		{"accepted", "Metadata is read from WALDO_UPLOAD_METADATA", true, false},
+		{"not accepted", "  --upload_token <value>  Upload token", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, &stderr)
+
+			ua := NewUploadAction(&UploadOptions{}, ioStreams)
+
+			ua.metadata = map[string]string{"team": "mobile"}
+			ua.agentUsage = probeAgentUsage(writeTestAgent(t, tt.usage), "upload")
+
+			ua.checkAgentUsage()
+
+			_, gotEnv := ua.enrichEnvironment()["WALDO_UPLOAD_METADATA"]
+
+			if gotEnv != tt.wantEnv {
+				t.Errorf("WALDO_UPLOAD_METADATA set = %v, want %v", gotEnv, tt.wantEnv)
+			}
+
+			if gotWarning := strings.Contains(stderr.String(), "does not accept upload metadata"); gotWarning != tt.wantWarning {
+				t.Errorf("warning = %q, want warning %v", stderr.String(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestTriggerActionAgentVariantName(t *testing.T) {
+	tests := []struct {
+		name        string
+		usage       string
+		wantEnv     bool
+		wantWarning bool
+	}{
+		{"accepted", "The variant is read from WALDO_TRIGGER_VARIANT_NAME", true, false},
+		{"not accepted", "  --rule_name <value>  Rule name", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stderr bytes.Buffer
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, &stderr)
+
+			ta := NewTriggerAction(&TriggerOptions{}, ioStreams)
+
+			ta.variantName = "staging"
+			ta.agentUsage = probeAgentUsage(writeTestAgent(t, tt.usage), "trigger")
+
+			_, gotEnv := ta.enrichEnvironment()["WALDO_TRIGGER_VARIANT_NAME"]
+
+			if gotEnv != tt.wantEnv {
+				t.Errorf("WALDO_TRIGGER_VARIANT_NAME set = %v, want %v", gotEnv, tt.wantEnv)
+			}
+
+			if gotWarning := strings.Contains(stderr.String(), "does not accept a trigger variant name"); gotWarning != tt.wantWarning {
+				t.Errorf("warning = %q, want warning %v", stderr.String(), tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestUploadActionAgentDescription(t *testing.T) {
+	var stderr bytes.Buffer
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, &stderr)
+
+	ua := NewUploadAction(&UploadOptions{}, ioStreams)
+
+	ua.description = "- Fix login"
+	ua.agentUsage = probeAgentUsage(writeTestAgent(t, "  --upload_token <value>  Upload token"), "upload")
+
+	ua.checkAgentUsage()
+
+	if _, ok := ua.enrichEnvironment()["WALDO_UPLOAD_DESCRIPTION"]; ok {
+		t.Errorf("WALDO_UPLOAD_DESCRIPTION set for agent that does not accept it")
+	}
+
+	if !strings.Contains(stderr.String(), "does not accept a build description") {
+		t.Errorf("warning = %q, want description warning", stderr.String())
+	}
+}
+
+func TestAgentOutputScanner(t *testing.T) {
+	tests := []struct {
+		name    string
+		noun    string
+		chunks  []string
+		wantID  string
+		wantURL string
+	}{
+		{
+			name:    "build split across writes",
+			noun:    "Build",
+			chunks:  []string{"Uploading…\nBuild ‘bld-", "1’ successfully uploaded to Waldo!\nSee https://app.waldo.com/applications/app-1/builds/bld-1.", "\n"},
+			wantID:  "bld-1",
+			wantURL: "https://app.waldo.com/applications/app-1/builds/bld-1"},
+		{
+			name:    "run without trailing newline",
+			noun:    "Run",
+			chunks:  []string{"POST https://api.waldo.com/suites\n", `Run "run-9" triggered: https://app.waldo.com/applications/app-1/sessions/run-9`},
+			wantID:  "run-9",
+			wantURL: "https://app.waldo.com/applications/app-1/sessions/run-9"},
+		{
+			name:   "nothing reported",
+			noun:   "Build",
+			chunks: []string{"Done\n"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner := newAgentOutputScanner(tt.noun)
+
+			for _, chunk := range tt.chunks {
+				scanner.Write([]byte(chunk))
+			}
+
+			scanner.Flush()
+
+			if scanner.id != tt.wantID || scanner.url != tt.wantURL {
+				t.Errorf("scanner = %q, %q, want %q, %q", scanner.id, scanner.url, tt.wantID, tt.wantURL)
+			}
+		})
+	}
+}
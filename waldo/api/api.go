@@ -1,14 +1,29 @@
 package api
 
 import (
+	"fmt"
 	"os"
+	"strings"
 )
 
 const (
+	defaultAppCenterEndpoint        = "https://appcenter.ms/api/v0.1"
 	defaultAuthenticateUserEndpoint = "https://api.waldo.com/1.0/users/me"
+	defaultCreateUploadEndpoint     = "https://api.waldo.com/1.0/uploads"
+	defaultEASEndpoint              = "https://api.expo.dev/graphql"
 	defaultFetchAppsEndpoint        = "https://api.waldo.com/1.0/applications"
+	defaultGitHubEndpoint           = "https://api.github.com"
+	defaultGitLabEndpoint           = "https://gitlab.com/api/v4"
 )
 
+func getAppCenterEndpoint() string {
+	if endpoint := os.Getenv("WALDO_APPCENTER_API_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	return defaultAppCenterEndpoint
+}
+
 func getAuthenticateUserEndpoint() string {
 	if endpoint := os.Getenv("WALDO_API_AUTHENTICATE_USER_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
 		return endpoint
@@ -17,6 +32,26 @@ func getAuthenticateUserEndpoint() string {
 	return defaultAuthenticateUserEndpoint
 }
 
+func getCompleteUploadEndpoint(uploadID string) string {
+	return fmt.Sprintf("%v/%v/complete", getCreateUploadEndpoint(), uploadID)
+}
+
+func getCreateUploadEndpoint() string {
+	if endpoint := os.Getenv("WALDO_API_CREATE_UPLOAD_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	return defaultCreateUploadEndpoint
+}
+
+func getEASEndpoint() string {
+	if endpoint := os.Getenv("WALDO_EAS_API_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
+		return endpoint
+	}
+
+	return defaultEASEndpoint
+}
+
 func getFetchAppsEndpoint() string {
 	if endpoint := os.Getenv("WALDO_API_FETCH_APPS_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
 		return endpoint
@@ -24,3 +59,41 @@ func getFetchAppsEndpoint() string {
 
 	return defaultFetchAppsEndpoint
 }
+
+func getGitHubEndpoint() string {
+	if endpoint := os.Getenv("WALDO_GITHUB_API_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	//
+	// Set by GitHub Actions (including on GitHub Enterprise Server):
+	//
+	if endpoint := os.Getenv("GITHUB_API_URL"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	return defaultGitHubEndpoint
+}
+
+func getGitLabEndpoint() string {
+	if endpoint := os.Getenv("WALDO_GITLAB_API_ENDPOINT_OVERRIDE"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	//
+	// Set by GitLab CI (including on self-managed GitLab):
+	//
+	if endpoint := os.Getenv("CI_API_V4_URL"); len(endpoint) > 0 {
+		return strings.TrimSuffix(endpoint, "/")
+	}
+
+	return defaultGitLabEndpoint
+}
+
+func makeAuthorization(token string) string {
+	if strings.HasPrefix(token, "u-") {
+		return fmt.Sprintf("Token %v", token)
+	}
+
+	return fmt.Sprintf("Upload-Token %v", token)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func CancelAppCenterBuild(ownerName, appName, buildID, apiToken string, verbose bool, ios *lib.IOStreams) error {
+	client := &http.Client{}
+
+	endpoint := fmt.Sprintf("%v/apps/%v/%v/builds/%v",
+		getAppCenterEndpoint(),
+		url.PathEscape(ownerName),
+		url.PathEscape(appName),
+		url.PathEscape(buildID))
+
+	req, err := http.NewRequest("PATCH", endpoint, bytes.NewReader([]byte(`{"status":"cancelling"}`)))
+
+	if err != nil {
+		return fmt.Errorf("Unable to cancel App Center build, error: %v", err)
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", data.FullVersion())
+	req.Header.Add("X-API-Token", apiToken)
+
+	if verbose {
+		lib.DumpRequest(ios, req, true)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return fmt.Errorf("Unable to cancel App Center build, error: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if verbose {
+		lib.DumpResponse(ios, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return fmt.Errorf("Unable to cancel App Center build, error: %v", rsp.Status)
+	}
+
+	return nil
+}
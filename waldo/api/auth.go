@@ -21,13 +21,13 @@ type AuthenticateUserResponse struct {
 
 //-----------------------------------------------------------------------------
 
-func AuthenticateUser(apiToken string, verbose bool, ios *lib.IOStreams) (string, error) {
+func AuthenticateUser(apiToken string, verbose bool, ios *lib.IOStreams) (*AuthenticateUserResponse, error) {
 	client := &http.Client{}
 
 	req, err := http.NewRequest("GET", getAuthenticateUserEndpoint(), nil)
 
 	if err != nil {
-		return "", fmt.Errorf("Unable to authenticate user, error: %v", err)
+		return nil, fmt.Errorf("Unable to authenticate user, error: %v", err)
 	}
 
 	req.Header.Add("Authorization", fmt.Sprintf("Token %v", apiToken))
@@ -40,7 +40,7 @@ func AuthenticateUser(apiToken string, verbose bool, ios *lib.IOStreams) (string
 	rsp, err := client.Do(req)
 
 	if err != nil {
-		return "", fmt.Errorf("Unable to authenticate user, error: %v", err)
+		return nil, fmt.Errorf("Unable to authenticate user, error: %v", err)
 	}
 
 	defer rsp.Body.Close()
@@ -52,16 +52,16 @@ func AuthenticateUser(apiToken string, verbose bool, ios *lib.IOStreams) (string
 	status := rsp.StatusCode
 
 	if status < 200 || status > 299 {
-		return "", fmt.Errorf("Unable to authenticate user, error: %v", rsp.Status)
+		return nil, fmt.Errorf("Unable to authenticate user, error: %v", rsp.Status)
 	}
 
 	aur, err := parseAuthenticateUserResponse(rsp)
 
 	if err != nil {
-		return "", fmt.Errorf("Unable to authenticate user, error: %v", err)
+		return nil, fmt.Errorf("Unable to authenticate user, error: %v", err)
 	}
 
-	return aur.fullName(), nil
+	return aur, nil
 }
 
 //-----------------------------------------------------------------------------
@@ -84,7 +84,7 @@ func parseAuthenticateUserResponse(rsp *http.Response) (*AuthenticateUserRespons
 
 //-----------------------------------------------------------------------------
 
-func (aur *AuthenticateUserResponse) fullName() string {
+func (aur *AuthenticateUserResponse) FullName() string {
 	if len(aur.FirstName) > 0 && len(aur.LastName) > 0 {
 		return aur.FirstName + " " + aur.LastName
 	}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	//
+	// The number of most recent finished builds to look through for the
+	// latest simulator/emulator build:
+	//
+	easLatestBuildsLimit = 20
+
+	easBuildFields = `id platform status distribution buildProfile appVersion appBuildVersion gitCommitHash artifacts { buildUrl }`
+
+	easFetchBuildQuery = `query FetchBuild($buildId: ID!) {
+  builds { byId(buildId: $buildId) { ` + easBuildFields + ` } }
+}`
+
+	easFetchLatestBuildQuery = `query FetchLatestBuild($appId: String!, $limit: Int!, $filter: BuildFilter) {
+  app { byId(appId: $appId) { builds(offset: 0, limit: $limit, filter: $filter) { ` + easBuildFields + ` } } }
+}`
+)
+
+//-----------------------------------------------------------------------------
+
+type EASBuild struct {
+	BuildID         string            `json:"id"`
+	Platform        string            `json:"platform"`
+	Status          string            `json:"status"`
+	Distribution    string            `json:"distribution,omitempty"`
+	BuildProfile    string            `json:"buildProfile,omitempty"`
+	AppVersion      string            `json:"appVersion,omitempty"`
+	AppBuildVersion string            `json:"appBuildVersion,omitempty"`
+	GitCommitHash   string            `json:"gitCommitHash,omitempty"`
+	Artifacts       *EASBuildArtifact `json:"artifacts,omitempty"`
+}
+
+type EASBuildArtifact struct {
+	BuildURL string `json:"buildUrl,omitempty"`
+}
+
+type FetchEASBuildResponse struct {
+	Data struct {
+		App *struct {
+			ByID *struct {
+				Builds []*EASBuild `json:"builds"`
+			} `json:"byId"`
+		} `json:"app,omitempty"`
+		Builds *struct {
+			ByID *EASBuild `json:"byId"`
+		} `json:"builds,omitempty"`
+	} `json:"data"`
+	Errors []*struct {
+		Message string `json:"message"`
+	} `json:"errors,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func FetchEASBuild(buildID, expoToken string, verbose bool, ios *lib.IOStreams) (*EASBuild, error) {
+	variables := map[string]any{
+		"buildId": buildID}
+
+	fer, err := fetchEAS(easFetchBuildQuery, variables, expoToken, verbose, ios)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fer.Data.Builds == nil || fer.Data.Builds.ByID == nil {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: build %q not found", buildID)
+	}
+
+	return fer.Data.Builds.ByID, nil
+}
+
+// FetchLatestEASBuild returns the most recent finished simulator/emulator
+// build of the given EAS project, skipping any more recent device builds.
+func FetchLatestEASBuild(projectID string, platform lib.Platform, expoToken string, verbose bool, ios *lib.IOStreams) (*EASBuild, error) {
+	filter := map[string]any{
+		"status": "FINISHED"}
+
+	if ptype := platformType(platform); len(ptype) > 0 {
+		filter["platform"] = strings.ToUpper(ptype)
+	}
+
+	//
+	// Only iOS builds have a simulator distribution; Android emulator builds
+	// are told apart by their APK artifact below:
+	//
+	if platform == lib.PlatformIos {
+		filter["distribution"] = "SIMULATOR"
+	}
+
+	variables := map[string]any{
+		"appId":  projectID,
+		"filter": filter,
+		"limit":  easLatestBuildsLimit}
+
+	fer, err := fetchEAS(easFetchLatestBuildQuery, variables, expoToken, verbose, ios)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if fer.Data.App != nil && fer.Data.App.ByID != nil {
+		for _, eb := range fer.Data.App.ByID.Builds {
+			if eb.IsSimulatorBuild() {
+				return eb, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("Unable to fetch EAS build, error: no finished simulator/emulator builds found for project %q", projectID)
+}
+
+//-----------------------------------------------------------------------------
+
+func (eb *EASBuild) ArtifactURL() string {
+	if eb.Artifacts != nil {
+		return eb.Artifacts.BuildURL
+	}
+
+	return ""
+}
+
+func (eb *EASBuild) IsSimulatorBuild() bool {
+	switch eb.ParsePlatform() {
+	case lib.PlatformAndroid:
+		return strings.HasSuffix(strings.ToLower(eb.artifactPath()), ".apk")
+
+	case lib.PlatformIos:
+		return eb.Distribution == "SIMULATOR"
+
+	default:
+		return false
+	}
+}
+
+func (eb *EASBuild) ParsePlatform() lib.Platform {
+	return lib.ParsePlatform(eb.Platform)
+}
+
+//-----------------------------------------------------------------------------
+
+func (eb *EASBuild) artifactPath() string {
+	path, _, _ := strings.Cut(eb.ArtifactURL(), "?")
+
+	return path
+}
+
+//-----------------------------------------------------------------------------
+
+func fetchEAS(query string, variables map[string]any, expoToken string, verbose bool, ios *lib.IOStreams) (*FetchEASBuildResponse, error) {
+	payload, err := json.Marshal(map[string]any{
+		"query":     query,
+		"variables": variables})
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", err)
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("POST", getEASEndpoint(), bytes.NewReader(payload))
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", err)
+	}
+
+	if len(expoToken) > 0 {
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %v", expoToken))
+	}
+
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", data.FullVersion())
+
+	if verbose {
+		lib.DumpRequest(ios, req, true)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if verbose {
+		lib.DumpResponse(ios, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", rsp.Status)
+	}
+
+	fer, err := parseFetchEASBuildResponse(rsp)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", err)
+	}
+
+	if len(fer.Errors) > 0 {
+		return nil, fmt.Errorf("Unable to fetch EAS build, error: %v", fer.Errors[0].Message)
+	}
+
+	return fer, nil
+}
+
+func parseFetchEASBuildResponse(rsp *http.Response) (*FetchEASBuildResponse, error) {
+	data, err := io.ReadAll(rsp.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fer := &FetchEASBuildResponse{}
+
+	if err = json.Unmarshal(data, fer); err != nil {
+		return nil, err
+	}
+
+	return fer, nil
+}
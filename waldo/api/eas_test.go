@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// easStandIn stands in for the EAS GraphQL API, answering every query with
+// the given builds and recording the variables of the last query.
+func easStandIn(t *testing.T, builds []*EASBuild) *map[string]any {
+	variables := make(map[string]any)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query     string         `json:"query"`
+			Variables map[string]any `json:"variables"`
+		}
+
+		json.NewDecoder(r.Body).Decode(&body)
+
+		variables = body.Variables
+
+		fer := &FetchEASBuildResponse{}
+
+		if strings.Contains(body.Query, "FetchLatestBuild") {
+			fer.Data.App = &struct {
+				ByID *struct {
+					Builds []*EASBuild `json:"builds"`
+				} `json:"byId"`
+			}{ByID: &struct {
+				Builds []*EASBuild `json:"builds"`
+			}{Builds: builds}}
+		} else {
+			for _, eb := range builds {
+				if eb.BuildID == body.Variables["buildId"] {
+					fer.Data.Builds = &struct {
+						ByID *EASBuild `json:"byId"`
+					}{ByID: eb}
+				}
+			}
+		}
+
+		json.NewEncoder(w).Encode(fer)
+	}))
+
+	t.Cleanup(server.Close)
+	t.Setenv("WALDO_EAS_API_ENDPOINT_OVERRIDE", server.URL)
+
+	return &variables
+}
+
+func makeEASBuild(buildID, platform, distribution, artifactURL string) *EASBuild {
+	return &EASBuild{
+		BuildID:      buildID,
+		Platform:     platform,
+		Status:       "FINISHED",
+		Distribution: distribution,
+		Artifacts:    &EASBuildArtifact{BuildURL: artifactURL}}
+}
+
+func TestFetchLatestEASBuild(t *testing.T) {
+	tests := []struct {
+		name             string
+		platform         lib.Platform
+		builds           []*EASBuild
+		wantBuildID      string
+		wantDistribution any
+	}{
+		{
+			name:     "skips newer iOS device build",
+			platform: lib.PlatformIos,
+			builds: []*EASBuild{
+				makeEASBuild("ios-device", "IOS", "STORE", "https://x/app.ipa"),
+				makeEASBuild("ios-sim", "IOS", "SIMULATOR", "https://x/app.tar.gz")},
+			wantBuildID:      "ios-sim",
+			wantDistribution: "SIMULATOR"},
+		{
+			name:     "skips newer Android bundle build",
+			platform: lib.PlatformAndroid,
+			builds: []*EASBuild{
+				makeEASBuild("android-aab", "ANDROID", "STORE", "https://x/app.aab"),
+				makeEASBuild("android-apk", "ANDROID", "INTERNAL", "https://x/app.apk?sig=1")},
+			wantBuildID: "android-apk"},
+		{
+			name:     "no simulator build",
+			platform: lib.PlatformUnknown,
+			builds: []*EASBuild{
+				makeEASBuild("ios-device", "IOS", "STORE", "https://x/app.ipa")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variables := easStandIn(t, tt.builds)
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			eb, err := FetchLatestEASBuild("proj-1", tt.platform, "", false, ioStreams)
+
+			if len(tt.wantBuildID) == 0 {
+				if err == nil || !strings.Contains(err.Error(), "no finished simulator/emulator builds") {
+					t.Fatalf("FetchLatestEASBuild() = %v, %v, want error", eb, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("FetchLatestEASBuild() error = %v", err)
+			}
+
+			if eb.BuildID != tt.wantBuildID {
+				t.Errorf("BuildID = %q, want %q", eb.BuildID, tt.wantBuildID)
+			}
+
+			filter, _ := (*variables)["filter"].(map[string]any)
+
+			if filter["status"] != "FINISHED" || filter["distribution"] != tt.wantDistribution {
+				t.Errorf("filter = %v", filter)
+			}
+
+			if (*variables)["appId"] != "proj-1" {
+				t.Errorf("appId = %v, want %q", (*variables)["appId"], "proj-1")
+			}
+		})
+	}
+}
+
+func TestFetchEASBuild(t *testing.T) {
+	easStandIn(t, []*EASBuild{makeEASBuild("bld-1", "IOS", "SIMULATOR", "https://x/app.tar.gz")})
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	eb, err := FetchEASBuild("bld-1", "", false, ioStreams)
+
+	if err != nil || eb.BuildID != "bld-1" || !eb.IsSimulatorBuild() || eb.ParsePlatform() != lib.PlatformIos {
+		t.Fatalf("FetchEASBuild() = %+v, %v", eb, err)
+	}
+
+	if _, err := FetchEASBuild("bld-2", "", false, ioStreams); err == nil || !strings.Contains(err.Error(), `build "bld-2" not found`) {
+		t.Errorf("FetchEASBuild() error = %v, want not found", err)
+	}
+}
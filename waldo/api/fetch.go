@@ -0,0 +1,258 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	fetchMaxGetAttempts = 3
+	fetchMaxRedirects   = 10
+)
+
+//-----------------------------------------------------------------------------
+
+// BuildFetcher downloads a build artifact from a remote URL. Given extract
+// limits, a gzipped tarball is extracted as it is downloaded, so that only
+// its contents are ever on disk. Any other artifact is streamed to a temporary
+// file, as both Waldo Agent and the direct upload (which must send the size
+// and SHA-256 of the artifact before any of its bytes) need a complete file,
+// as does validating it (an APK or zip archive is read from its end).
+type BuildFetcher struct {
+	buildPath     string
+	buildURL      string
+	errorPrefix   string
+	extractLimits *lib.ExtractLimits
+	headers       []string
+	ioStreams     *lib.IOStreams
+	token         string
+	verbose       bool
+	workingPath   string
+}
+
+//-----------------------------------------------------------------------------
+
+func NewBuildFetcher(buildURL string, headers []string, token string, extractLimits *lib.ExtractLimits, errorPrefix string, verbose bool, ioStreams *lib.IOStreams) *BuildFetcher {
+	return &BuildFetcher{
+		buildURL:      buildURL,
+		errorPrefix:   errorPrefix,
+		extractLimits: extractLimits,
+		headers:       headers,
+		ioStreams:     ioStreams,
+		token:         token,
+		verbose:       verbose}
+}
+
+//-----------------------------------------------------------------------------
+
+func (bf *BuildFetcher) Cleanup() {
+	if len(bf.workingPath) > 0 {
+		os.RemoveAll(bf.workingPath)
+	}
+}
+
+func (bf *BuildFetcher) Fetch() (string, error) {
+	if err := bf.prepareTarget(); err != nil {
+		return "", err
+	}
+
+	if err := bf.fetchBuildWithRetry(); err != nil {
+		return "", err
+	}
+
+	return bf.buildPath, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (bf *BuildFetcher) addHeaders(req *http.Request) error {
+	for _, header := range bf.headers {
+		name, value, found := strings.Cut(header, ":")
+
+		name = strings.TrimSpace(name)
+
+		if !found || len(name) == 0 {
+			return fmt.Errorf("Invalid header syntax: %q", header)
+		}
+
+		req.Header.Add(name, strings.TrimSpace(value))
+	}
+
+	if len(bf.token) > 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %v", bf.token))
+	}
+
+	req.Header.Set("User-Agent", data.FullVersion())
+
+	return nil
+}
+
+// checkRedirect drops the headers given with --from_url_header (and the
+// --from_url_token) when a redirect leaves the original host, as they are
+// meant for that host only. (Go itself only drops a few well-known ones.)
+func (bf *BuildFetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= fetchMaxRedirects {
+		return errors.New("stopped after too many redirects")
+	}
+
+	if req.URL.Host == via[0].URL.Host {
+		return nil
+	}
+
+	for _, header := range bf.headers {
+		name, _, _ := strings.Cut(header, ":")
+
+		req.Header.Del(strings.TrimSpace(name))
+	}
+
+	if len(bf.token) > 0 {
+		req.Header.Del("Authorization")
+	}
+
+	return nil
+}
+
+func (bf *BuildFetcher) determineFileName(rsp *http.Response) string {
+	if _, params, err := mime.ParseMediaType(rsp.Header.Get("Content-Disposition")); err == nil {
+		if name := filepath.Base(params["filename"]); isUsableFileName(name) {
+			return name
+		}
+	}
+
+	if u, err := url.Parse(bf.buildURL); err == nil {
+		if name := path.Base(u.Path); isUsableFileName(name) {
+			return name
+		}
+	}
+
+	return "build"
+}
+
+func (bf *BuildFetcher) fetchBuild(retryAllowed bool) (bool, error) {
+	bf.ioStreams.Printf("\nDownloading build from %q\n", bf.buildURL)
+
+	client := &http.Client{CheckRedirect: bf.checkRedirect}
+
+	req, err := http.NewRequest("GET", bf.buildURL, nil)
+
+	if err != nil {
+		return false, fmt.Errorf("Unable to download build, error: %v, url: %q", err, bf.buildURL)
+	}
+
+	if err := bf.addHeaders(req); err != nil {
+		return false, fmt.Errorf("Unable to download build, error: %v, url: %q", err, bf.buildURL)
+	}
+
+	if bf.verbose {
+		lib.DumpRequest(bf.ioStreams, req, false)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return retryAllowed, fmt.Errorf("Unable to download build, error: %v, url: %q", err, bf.buildURL)
+	}
+
+	defer rsp.Body.Close()
+
+	if bf.verbose {
+		lib.DumpResponse(bf.ioStreams, rsp, false)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return retryAllowed && lib.ShouldRetry(rsp), fmt.Errorf("Unable to download build, HTTP status: %d, url: %q", status, bf.buildURL)
+	}
+
+	fileName := bf.determineFileName(rsp)
+
+	if bf.extractLimits != nil && isTarGzFileName(fileName) {
+		bf.buildPath = filepath.Join(bf.workingPath, "extracted")
+
+		bf.ioStreams.Printf("\nExtracting build from %q as it is downloaded\n", fileName)
+
+		if err := os.Mkdir(bf.buildPath, 0755); err != nil {
+			return false, err
+		}
+
+		if err := lib.ExtractTarGzFrom(rsp.Body, bf.buildPath, bf.extractLimits); err != nil {
+			return false, fmt.Errorf("Unable to download build, error: %v, url: %q", err, bf.buildURL)
+		}
+
+		return false, nil
+	}
+
+	bf.buildPath = filepath.Join(bf.workingPath, fileName)
+
+	if err := bf.saveResponseBody(rsp, bf.buildPath); err != nil {
+		return false, fmt.Errorf("Unable to download build, error: %v, url: %q", err, bf.buildURL)
+	}
+
+	return false, nil
+}
+
+func (bf *BuildFetcher) fetchBuildWithRetry() error {
+	for attempts := 1; attempts <= fetchMaxGetAttempts; attempts++ {
+		retry, err := bf.fetchBuild(attempts < fetchMaxGetAttempts)
+
+		if !retry || err == nil {
+			return err
+		}
+
+		bf.ioStreams.EmitError(bf.errorPrefix, err)
+
+		bf.ioStreams.Printf("\nFailed download attempts: %d -- retrying\n", attempts)
+	}
+
+	return nil
+}
+
+func (bf *BuildFetcher) prepareTarget() error {
+	workingPath, err := os.MkdirTemp("", "WaldoGoCLI-fetch-")
+
+	if err != nil {
+		return err
+	}
+
+	bf.workingPath = workingPath
+
+	return nil
+}
+
+func (bf *BuildFetcher) saveResponseBody(rsp *http.Response, path string) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	defer file.Close()
+
+	_, err = io.Copy(file, rsp.Body)
+
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+func isTarGzFileName(name string) bool {
+	lowerName := strings.ToLower(name)
+
+	return strings.HasSuffix(lowerName, ".tar.gz") || strings.HasSuffix(lowerName, ".tgz")
+}
+
+func isUsableFileName(name string) bool {
+	return len(name) > 0 && name != "." && name != "/" && name != ".." && !strings.ContainsAny(name, `/\`)
+}
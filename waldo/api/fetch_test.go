@@ -0,0 +1,154 @@
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+func TestBuildFetcherRedirectHeaders(t *testing.T) {
+	var got http.Header
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+
+		w.Write([]byte("fake build"))
+	}))
+
+	defer target.Close()
+
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/cross-host/Test.apk":
+			http.Redirect(w, r, target.URL+"/Test.apk", http.StatusFound)
+
+		case "/same-host/Test.apk":
+			http.Redirect(w, r, "/Test.apk", http.StatusFound)
+
+		default:
+			got = r.Header.Clone()
+
+			w.Write([]byte("fake build"))
+		}
+	}))
+
+	defer origin.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		wantHeaders bool
+	}{
+		{"cross-host redirect", "/cross-host/Test.apk", false},
+		{"same-host redirect", "/same-host/Test.apk", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			bf := NewBuildFetcher(origin.URL+tt.path, []string{"X-Api-Key: secret"}, "token", nil, "waldo", false, ioStreams)
+
+			defer bf.Cleanup()
+
+			path, err := bf.Fetch()
+
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+
+			if contents, _ := os.ReadFile(path); string(contents) != "fake build" {
+				t.Errorf("fetched build = %q", contents)
+			}
+
+			for name, want := range map[string]string{"X-Api-Key": "secret", "Authorization": "Bearer token"} {
+				if forwarded := got.Get(name) == want; forwarded != tt.wantHeaders {
+					t.Errorf("%v forwarded = %v, want %v", name, forwarded, tt.wantHeaders)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildFetcherExtractTarGz(t *testing.T) {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	for _, name := range []string{"Sample.app/Info.plist", "Sample.app/Sample"} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+		tw.Write([]byte("data"))
+	}
+
+	tw.Close()
+	gw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		limits  *lib.ExtractLimits
+		wantDir bool
+		wantErr string
+	}{
+		{name: "saved without limits", wantDir: false},
+		{name: "extracted as downloaded", limits: &lib.ExtractLimits{MaxEntries: 10}, wantDir: true},
+		{name: "exceeding limits", limits: &lib.ExtractLimits{MaxEntries: 1}, wantErr: "maximum number of entries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			bf := NewBuildFetcher(server.URL+"/Sample.tar.gz", nil, "", tt.limits, "waldo", false, ioStreams)
+
+			defer bf.Cleanup()
+
+			path, err := bf.Fetch()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Fetch() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+
+			if isDir := lib.IsDirectory(path); isDir != tt.wantDir {
+				t.Fatalf("Fetch() = %q, directory = %v, want %v", path, isDir, tt.wantDir)
+			}
+
+			if !tt.wantDir {
+				return
+			}
+
+			if !lib.IsRegularFile(filepath.Join(path, "Sample.app", "Info.plist")) {
+				t.Errorf("Sample.app not extracted to %q", path)
+			}
+
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tar.gz")); len(matches) > 0 {
+				t.Errorf("tarball saved: %v", matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	gitHubCommentsPerPage = 100
+)
+
+//-----------------------------------------------------------------------------
+
+type GitHubCheckRun struct {
+	Conclusion string               `json:"conclusion"`
+	DetailsURL string               `json:"details_url,omitempty"`
+	HeadSHA    string               `json:"head_sha"`
+	Name       string               `json:"name"`
+	Output     GitHubCheckRunOutput `json:"output"`
+	Status     string               `json:"status"`
+}
+
+type GitHubCheckRunOutput struct {
+	Summary string `json:"summary"`
+	Title   string `json:"title"`
+}
+
+type GitHubClient struct {
+	ioStreams  *lib.IOStreams
+	repository string // owner/name
+	token      string
+	verbose    bool
+}
+
+type GitHubComment struct {
+	Body      string `json:"body"`
+	CommentID int64  `json:"id,omitempty"`
+}
+
+type GitHubCommitStatus struct {
+	Context     string `json:"context"`
+	Description string `json:"description,omitempty"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func NewGitHubClient(repository, token string, verbose bool, ioStreams *lib.IOStreams) *GitHubClient {
+	return &GitHubClient{
+		ioStreams:  ioStreams,
+		repository: repository,
+		token:      token,
+		verbose:    verbose}
+}
+
+//-----------------------------------------------------------------------------
+
+func (gc *GitHubClient) CreateCheckRun(checkRun *GitHubCheckRun) (int, error) {
+	return gc.send("POST", gc.repoURL("check-runs"), checkRun, nil)
+}
+
+func (gc *GitHubClient) CreateComment(issueNumber string, body string) error {
+	_, err := gc.send("POST", gc.repoURL("issues", issueNumber, "comments"), &GitHubComment{Body: body}, nil)
+
+	return err
+}
+
+func (gc *GitHubClient) CreateCommitStatus(sha string, status *GitHubCommitStatus) error {
+	_, err := gc.send("POST", gc.repoURL("statuses", sha), status, nil)
+
+	return err
+}
+
+func (gc *GitHubClient) FindComment(issueNumber, marker string) (*GitHubComment, error) {
+	for page := 1; ; page++ {
+		var comments []*GitHubComment
+
+		url := fmt.Sprintf("%v?per_page=%d&page=%d", gc.repoURL("issues", issueNumber, "comments"), gitHubCommentsPerPage, page)
+
+		if _, err := gc.send("GET", url, nil, &comments); err != nil {
+			return nil, err
+		}
+
+		for _, comment := range comments {
+			if strings.Contains(comment.Body, marker) {
+				return comment, nil
+			}
+		}
+
+		if len(comments) < gitHubCommentsPerPage {
+			return nil, nil
+		}
+	}
+}
+
+func (gc *GitHubClient) UpdateComment(commentID int64, body string) error {
+	_, err := gc.send("PATCH", gc.repoURL("issues", "comments", fmt.Sprintf("%d", commentID)), &GitHubComment{Body: body}, nil)
+
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+func (gc *GitHubClient) repoURL(components ...string) string {
+	return getGitHubEndpoint() + "/repos/" + gc.repository + "/" + strings.Join(components, "/")
+}
+
+func (gc *GitHubClient) send(method, url string, body, result any) (int, error) {
+	var reader io.Reader
+
+	if body != nil {
+		payload, err := json.Marshal(body)
+
+		if err != nil {
+			return 0, err
+		}
+
+		reader = bytes.NewReader(payload)
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest(method, url, reader)
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Add("Accept", "application/vnd.github+json")
+	req.Header.Add("Authorization", "Bearer "+gc.token)
+	req.Header.Add("User-Agent", data.FullVersion())
+	req.Header.Add("X-GitHub-Api-Version", "2022-11-28")
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	if gc.verbose {
+		lib.DumpRequest(gc.ioStreams, req, true)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rsp.Body.Close()
+
+	if gc.verbose {
+		lib.DumpResponse(gc.ioStreams, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return status, fmt.Errorf("%v %v: %v", method, url, rsp.Status)
+	}
+
+	if result == nil {
+		return status, nil
+	}
+
+	rspData, err := io.ReadAll(rsp.Body)
+
+	if err != nil {
+		return status, err
+	}
+
+	return status, json.Unmarshal(rspData, result)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	gitLabNotesPerPage = 100
+)
+
+//-----------------------------------------------------------------------------
+
+type GitLabClient struct {
+	ioStreams *lib.IOStreams
+	projectID string // numeric ID or namespace/path
+	token     string
+	verbose   bool
+}
+
+type GitLabCommitStatus struct {
+	Description string `json:"description,omitempty"`
+	Name        string `json:"name"`
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+}
+
+type GitLabNote struct {
+	Body   string `json:"body"`
+	NoteID int64  `json:"id,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func NewGitLabClient(projectID, token string, verbose bool, ioStreams *lib.IOStreams) *GitLabClient {
+	return &GitLabClient{
+		ioStreams: ioStreams,
+		projectID: projectID,
+		token:     token,
+		verbose:   verbose}
+}
+
+//-----------------------------------------------------------------------------
+
+func (gc *GitLabClient) CreateCommitStatus(sha string, status *GitLabCommitStatus) error {
+	return gc.send("POST", gc.projectURL("statuses", sha), status, nil)
+}
+
+func (gc *GitLabClient) CreateNote(mergeRequestIID, body string) error {
+	return gc.send("POST", gc.projectURL("merge_requests", mergeRequestIID, "notes"), &GitLabNote{Body: body}, nil)
+}
+
+func (gc *GitLabClient) FindNote(mergeRequestIID, marker string) (*GitLabNote, error) {
+	for page := 1; ; page++ {
+		var notes []*GitLabNote
+
+		url := fmt.Sprintf("%v?per_page=%d&page=%d", gc.projectURL("merge_requests", mergeRequestIID, "notes"), gitLabNotesPerPage, page)
+
+		if err := gc.send("GET", url, nil, &notes); err != nil {
+			return nil, err
+		}
+
+		for _, note := range notes {
+			if strings.Contains(note.Body, marker) {
+				return note, nil
+			}
+		}
+
+		if len(notes) < gitLabNotesPerPage {
+			return nil, nil
+		}
+	}
+}
+
+func (gc *GitLabClient) UpdateNote(mergeRequestIID string, noteID int64, body string) error {
+	return gc.send("PUT", gc.projectURL("merge_requests", mergeRequestIID, "notes", fmt.Sprintf("%d", noteID)), &GitLabNote{Body: body}, nil)
+}
+
+//-----------------------------------------------------------------------------
+
+func (gc *GitLabClient) projectURL(components ...string) string {
+	return getGitLabEndpoint() + "/projects/" + url.PathEscape(gc.projectID) + "/" + strings.Join(components, "/")
+}
+
+func (gc *GitLabClient) send(method, url string, body, result any) error {
+	var reader io.Reader
+
+	if body != nil {
+		payload, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		reader = bytes.NewReader(payload)
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest(method, url, reader)
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("PRIVATE-TOKEN", gc.token)
+	req.Header.Add("User-Agent", data.FullVersion())
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	if gc.verbose {
+		lib.DumpRequest(gc.ioStreams, req, true)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if gc.verbose {
+		lib.DumpResponse(gc.ioStreams, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return fmt.Errorf("%v %v: %v", method, url, rsp.Status)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	rspData, err := io.ReadAll(rsp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(rspData, result)
+}
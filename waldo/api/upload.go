@@ -0,0 +1,399 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	uploadMaxPutAttempts = 3
+
+	uploadMultipartThreshold = 64 * 1024 * 1024 // 64 MiB
+	uploadPartSize           = 16 * 1024 * 1024 // 16 MiB
+)
+
+//-----------------------------------------------------------------------------
+
+type BuildUploader struct {
+	buildPath   string
+	errorPrefix string
+	info        *UploadInfo
+	ioStreams   *lib.IOStreams
+	uploadToken string
+	verbose     bool
+	workingPath string
+
+	filePath string
+	fileSize int64
+	platform lib.Platform
+	sha256   string
+}
+
+type CompleteUploadResponse struct {
+	BuildID  string `json:"id"`
+	BuildURL string `json:"url,omitempty"`
+}
+
+type CreateUploadResponse struct {
+	UploadID string        `json:"id"`
+	Parts    []*UploadPart `json:"parts"`
+}
+
+type UploadInfo struct {
+	AppID       string
+	Description string
+	GitBranch   string
+	GitCommit   string
+	Metadata    map[string]string
+	VariantName string
+}
+
+type UploadPart struct {
+	Number int    `json:"partNumber"`
+	URL    string `json:"url,omitempty"`
+	ETag   string `json:"etag,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+type completeUploadRequest struct {
+	AppID       string            `json:"appId,omitempty"`
+	Description string            `json:"description,omitempty"`
+	GitBranch   string            `json:"gitBranch,omitempty"`
+	GitCommit   string            `json:"gitCommit,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Parts       []*UploadPart     `json:"parts"`
+	VariantName string            `json:"variantName,omitempty"`
+}
+
+type createUploadRequest struct {
+	AppID     string `json:"appId,omitempty"`
+	FileName  string `json:"fileName"`
+	FileSize  int64  `json:"fileSize"`
+	PartCount int    `json:"partCount"`
+	PartSize  int64  `json:"partSize"`
+	Platform  string `json:"platform,omitempty"`
+	SHA256    string `json:"sha256"`
+}
+
+//-----------------------------------------------------------------------------
+
+func NewBuildUploader(buildPath, uploadToken string, info *UploadInfo, errorPrefix string, verbose bool, ioStreams *lib.IOStreams) *BuildUploader {
+	return &BuildUploader{
+		buildPath:   buildPath,
+		errorPrefix: errorPrefix,
+		info:        info,
+		ioStreams:   ioStreams,
+		uploadToken: uploadToken,
+		verbose:     verbose}
+}
+
+//-----------------------------------------------------------------------------
+
+func (bu *BuildUploader) Cleanup() {
+	if len(bu.workingPath) > 0 {
+		os.RemoveAll(bu.workingPath)
+	}
+}
+
+func (bu *BuildUploader) Upload() (*CompleteUploadResponse, error) {
+	if err := bu.prepareSource(); err != nil {
+		return nil, err
+	}
+
+	bu.ioStreams.Printf("\nUploading build to Waldo\n")
+
+	cur, err := bu.createUpload()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, part := range cur.Parts {
+		if err := bu.uploadPartWithRetry(part, len(cur.Parts)); err != nil {
+			return nil, err
+		}
+	}
+
+	return bu.completeUpload(cur)
+}
+
+//-----------------------------------------------------------------------------
+
+func (bu *BuildUploader) completeUpload(cur *CreateUploadResponse) (*CompleteUploadResponse, error) {
+	parts := lib.Map(cur.Parts, func(part *UploadPart) *UploadPart {
+		return &UploadPart{
+			Number: part.Number,
+			ETag:   part.ETag}
+	})
+
+	body := &completeUploadRequest{
+		AppID:       bu.info.AppID,
+		Description: bu.info.Description,
+		GitBranch:   bu.info.GitBranch,
+		GitCommit:   bu.info.GitCommit,
+		Metadata:    bu.info.Metadata,
+		Parts:       parts,
+		VariantName: bu.info.VariantName}
+
+	result := &CompleteUploadResponse{}
+
+	if err := bu.postJSON(getCompleteUploadEndpoint(cur.UploadID), body, result); err != nil {
+		return nil, fmt.Errorf("Unable to complete upload, error: %v", err)
+	}
+
+	return result, nil
+}
+
+func (bu *BuildUploader) createUpload() (*CreateUploadResponse, error) {
+	partCount := 1
+	partSize := bu.fileSize
+
+	if bu.fileSize > uploadMultipartThreshold {
+		partSize = uploadPartSize
+		partCount = int((bu.fileSize + partSize - 1) / partSize)
+	}
+
+	body := &createUploadRequest{
+		AppID:     bu.info.AppID,
+		FileName:  filepath.Base(bu.filePath),
+		FileSize:  bu.fileSize,
+		PartCount: partCount,
+		PartSize:  partSize,
+		Platform:  platformType(bu.platform),
+		SHA256:    bu.sha256}
+
+	result := &CreateUploadResponse{}
+
+	if err := bu.postJSON(getCreateUploadEndpoint(), body, result); err != nil {
+		return nil, fmt.Errorf("Unable to create upload, error: %v", err)
+	}
+
+	if len(result.UploadID) == 0 || len(result.Parts) != partCount {
+		return nil, fmt.Errorf("Unable to create upload, error: unexpected response for %d part(s)", partCount)
+	}
+
+	seen := make(map[int]bool, partCount)
+
+	for _, part := range result.Parts {
+		if part.Number < 1 || part.Number > partCount || len(part.URL) == 0 {
+			return nil, fmt.Errorf("Unable to create upload, error: invalid part %d", part.Number)
+		}
+
+		if seen[part.Number] {
+			return nil, fmt.Errorf("Unable to create upload, error: duplicate part %d", part.Number)
+		}
+
+		seen[part.Number] = true
+	}
+
+	return result, nil
+}
+
+func (bu *BuildUploader) determinePlatform() lib.Platform {
+	switch strings.ToLower(filepath.Ext(bu.buildPath)) {
+	case ".apk":
+		return lib.PlatformAndroid
+
+	case ".app":
+		return lib.PlatformIos
+
+	default:
+		return lib.PlatformUnknown
+	}
+}
+
+func (bu *BuildUploader) hashFile() (string, int64, error) {
+	file, err := os.Open(bu.filePath)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	size, err := io.Copy(hash, file)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
+func (bu *BuildUploader) postJSON(url string, body, result any) error {
+	payload, err := json.Marshal(body)
+
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+
+	if err != nil {
+		return err
+	}
+
+	req.Header.Add("Authorization", makeAuthorization(bu.uploadToken))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("User-Agent", data.FullVersion())
+
+	if bu.verbose {
+		lib.DumpRequest(bu.ioStreams, req, true)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return err
+	}
+
+	defer rsp.Body.Close()
+
+	if bu.verbose {
+		lib.DumpResponse(bu.ioStreams, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return fmt.Errorf("%v", rsp.Status)
+	}
+
+	rspData, err := io.ReadAll(rsp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(rspData, result)
+}
+
+func (bu *BuildUploader) prepareSource() error {
+	bu.platform = bu.determinePlatform()
+
+	if lib.IsDirectory(bu.buildPath) {
+		workingPath, err := os.MkdirTemp("", "WaldoGoCLI-upload-")
+
+		if err != nil {
+			return err
+		}
+
+		bu.workingPath = workingPath
+		bu.filePath = filepath.Join(workingPath, filepath.Base(bu.buildPath)+".zip")
+
+		if err := lib.ZipDirectory(bu.buildPath, bu.filePath); err != nil {
+			return fmt.Errorf("Unable to archive build, error: %v, path: %q", err, bu.buildPath)
+		}
+	} else if lib.IsRegularFile(bu.buildPath) {
+		bu.filePath = bu.buildPath
+	} else {
+		return fmt.Errorf("Unable to read build, path: %q", bu.buildPath)
+	}
+
+	sha256, size, err := bu.hashFile()
+
+	if err != nil {
+		return fmt.Errorf("Unable to read build, error: %v, path: %q", err, bu.filePath)
+	}
+
+	bu.fileSize = size
+	bu.sha256 = sha256
+
+	return nil
+}
+
+func (bu *BuildUploader) uploadPart(part *UploadPart, partCount int, retryAllowed bool) (bool, error) {
+	offset := int64(part.Number-1) * uploadPartSize
+	length := bu.fileSize - offset
+
+	if partCount > 1 && length > uploadPartSize {
+		length = uploadPartSize
+	}
+
+	if partCount > 1 {
+		bu.ioStreams.Printf("\nUploading part %d of %d\n", part.Number, partCount)
+	}
+
+	file, err := os.Open(bu.filePath)
+
+	if err != nil {
+		return false, fmt.Errorf("Unable to upload build, error: %v", err)
+	}
+
+	defer file.Close()
+
+	client := &http.Client{}
+
+	req, err := http.NewRequest("PUT", part.URL, io.NewSectionReader(file, offset, length))
+
+	if err != nil {
+		return false, fmt.Errorf("Unable to upload build, error: %v", err)
+	}
+
+	req.ContentLength = length
+
+	if bu.verbose {
+		lib.DumpRequest(bu.ioStreams, req, false)
+	}
+
+	rsp, err := client.Do(req)
+
+	if err != nil {
+		return retryAllowed, fmt.Errorf("Unable to upload build, error: %v", err)
+	}
+
+	defer rsp.Body.Close()
+
+	if bu.verbose {
+		lib.DumpResponse(bu.ioStreams, rsp, true)
+	}
+
+	status := rsp.StatusCode
+
+	if status < 200 || status > 299 {
+		return retryAllowed && lib.ShouldRetry(rsp), fmt.Errorf("Unable to upload build, HTTP status: %d", status)
+	}
+
+	part.ETag = rsp.Header.Get("ETag")
+
+	//
+	// The ETag of each part is required to complete the upload:
+	//
+	if len(part.ETag) == 0 {
+		return false, fmt.Errorf("Unable to upload build, error: no ETag returned for part %d", part.Number)
+	}
+
+	return false, nil
+}
+
+func (bu *BuildUploader) uploadPartWithRetry(part *UploadPart, partCount int) error {
+	for attempts := 1; attempts <= uploadMaxPutAttempts; attempts++ {
+		retry, err := bu.uploadPart(part, partCount, attempts < uploadMaxPutAttempts)
+
+		if !retry || err == nil {
+			return err
+		}
+
+		bu.ioStreams.EmitError(bu.errorPrefix, err)
+
+		bu.ioStreams.Printf("\nFailed upload attempts: %d -- retrying\n", attempts)
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// uploadStandIn stands in for both the Waldo upload API and the S3 bucket the
+// pre-signed part URLs point to.
+type uploadStandIn struct {
+	duplicate  bool // list the first part in place of the last
+	etag       string
+	failPuts   int // number of PUTs to fail with 503 before succeeding
+	mutex      sync.Mutex
+	completed  *completeUploadRequest
+	created    *createUploadRequest
+	partBodies map[int][]byte
+	puts       int
+	server     *httptest.Server
+}
+
+func newUploadStandIn(t *testing.T) *uploadStandIn {
+	si := &uploadStandIn{
+		etag:       `"abc123"`,
+		partBodies: make(map[int][]byte)}
+
+	si.server = httptest.NewServer(http.HandlerFunc(si.serveHTTP))
+
+	t.Cleanup(si.server.Close)
+	t.Setenv("WALDO_API_CREATE_UPLOAD_ENDPOINT_OVERRIDE", si.server.URL+"/uploads")
+
+	return si
+}
+
+func (si *uploadStandIn) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/uploads":
+		si.created = &createUploadRequest{}
+
+		json.NewDecoder(r.Body).Decode(si.created)
+
+		var parts []*UploadPart
+
+		for number := 1; number <= si.created.PartCount; number++ {
+			parts = append(parts, &UploadPart{
+				Number: number,
+				URL:    fmt.Sprintf("%v/s3/part/%d", si.server.URL, number)})
+		}
+
+		if si.duplicate {
+			parts[len(parts)-1].Number = 1
+		}
+
+		json.NewEncoder(w).Encode(&CreateUploadResponse{UploadID: "upl-1", Parts: parts})
+
+	case r.Method == "PUT" && strings.HasPrefix(r.URL.Path, "/s3/part/"):
+		si.puts++
+
+		if si.puts <= si.failPuts {
+			w.WriteHeader(http.StatusServiceUnavailable)
+
+			return
+		}
+
+		var number int
+
+		fmt.Sscanf(r.URL.Path, "/s3/part/%d", &number)
+
+		si.partBodies[number], _ = io.ReadAll(r.Body)
+
+		if len(si.etag) > 0 {
+			w.Header().Set("ETag", si.etag)
+		}
+
+	case r.Method == "POST" && r.URL.Path == "/uploads/upl-1/complete":
+		si.completed = &completeUploadRequest{}
+
+		json.NewDecoder(r.Body).Decode(si.completed)
+
+		json.NewEncoder(w).Encode(&CompleteUploadResponse{BuildID: "bld-1", BuildURL: "https://app.waldo.com/b/bld-1"})
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func writeTestBuild(t *testing.T, contents string) string {
+	buildPath := filepath.Join(t.TempDir(), "Test.apk")
+
+	if err := os.WriteFile(buildPath, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return buildPath
+}
+
+func TestBuildUploaderUpload(t *testing.T) {
+	tests := []struct {
+		name      string
+		etag      string
+		failPuts  int
+		wantErr   string
+		wantPuts  int
+		completed bool
+	}{
+		{name: "success", etag: `"abc123"`, wantPuts: 1, completed: true},
+		{name: "retry after 503", etag: `"abc123"`, failPuts: 1, wantPuts: 2, completed: true},
+		{name: "missing ETag", etag: "", wantErr: "no ETag returned for part 1", wantPuts: 1},
+		{name: "too many failures", etag: `"abc123"`, failPuts: uploadMaxPutAttempts, wantErr: "HTTP status: 503", wantPuts: uploadMaxPutAttempts},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			si := newUploadStandIn(t)
+
+			si.etag = tt.etag
+			si.failPuts = tt.failPuts
+
+			info := &UploadInfo{GitCommit: "0123abc", VariantName: "debug"}
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			bu := NewBuildUploader(writeTestBuild(t, "fake build"), "0123456789abcdef0123456789abcdef", info, "waldo", false, ioStreams)
+
+			defer bu.Cleanup()
+
+			result, err := bu.Upload()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Upload() error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("Upload() error = %v", err)
+			}
+
+			if si.puts != tt.wantPuts {
+				t.Errorf("PUT count = %d, want %d", si.puts, tt.wantPuts)
+			}
+
+			if (si.completed != nil) != tt.completed {
+				t.Fatalf("upload completed = %v, want %v", si.completed != nil, tt.completed)
+			}
+
+			if !tt.completed {
+				return
+			}
+
+			if result.BuildID != "bld-1" {
+				t.Errorf("BuildID = %q, want %q", result.BuildID, "bld-1")
+			}
+
+			if got := string(si.partBodies[1]); got != "fake build" {
+				t.Errorf("part body = %q, want %q", got, "fake build")
+			}
+
+			if si.created.FileSize != int64(len("fake build")) || si.created.Platform != "android" {
+				t.Errorf("create request = %+v", si.created)
+			}
+
+			if len(si.completed.Parts) != 1 || si.completed.Parts[0].ETag != tt.etag {
+				t.Errorf("complete parts = %+v, want ETag %q", si.completed.Parts, tt.etag)
+			}
+
+			if si.completed.GitCommit != "0123abc" || si.completed.VariantName != "debug" {
+				t.Errorf("complete request = %+v", si.completed)
+			}
+		})
+	}
+}
+
+func TestBuildUploaderDuplicatePart(t *testing.T) {
+	si := newUploadStandIn(t)
+
+	si.duplicate = true
+
+	//
+	// Only a multipart upload has more than one part to duplicate:
+	//
+	buildPath := writeTestBuild(t, "")
+
+	if err := os.Truncate(buildPath, uploadMultipartThreshold+1); err != nil {
+		t.Fatal(err)
+	}
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	bu := NewBuildUploader(buildPath, "0123456789abcdef0123456789abcdef", &UploadInfo{}, "waldo", false, ioStreams)
+
+	defer bu.Cleanup()
+
+	if _, err := bu.Upload(); err == nil || !strings.Contains(err.Error(), "duplicate part 1") {
+		t.Fatalf("Upload() error = %v, want duplicate part", err)
+	}
+
+	if si.puts != 0 || si.completed != nil {
+		t.Errorf("PUT count = %d, completed = %v, want neither", si.puts, si.completed != nil)
+	}
+}
@@ -0,0 +1,178 @@
+package waldo
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+)
+
+type AppCenterBuildOptions struct {
+	Upload UploadOptions
+}
+
+type AppCenterBuildAction struct {
+	ioStreams *lib.IOStreams
+	options   *AppCenterBuildOptions
+
+	appName       string
+	configuration string
+	dataPath      string
+	project       string
+	scheme        string
+	xcodeOptions  []string
+}
+
+//-----------------------------------------------------------------------------
+
+func NewAppCenterBuildAction(options *AppCenterBuildOptions, ioStreams *lib.IOStreams) *AppCenterBuildAction {
+	return &AppCenterBuildAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+//-----------------------------------------------------------------------------
+
+func (aba *AppCenterBuildAction) Perform() error {
+	if err := aba.processSettings(); err != nil {
+		return err
+	}
+
+	//
+	// The scheme name may contain characters (such as “/”) that are not valid
+	// in a file name, so it is not used for the temporary directory:
+	//
+	dataPath, err := os.MkdirTemp("", "WaldoGoCLI-appcenter-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(dataPath)
+
+	aba.dataPath = dataPath
+
+	if err := aba.createSimBuild(); err != nil {
+		return err
+	}
+
+	if err := aba.uploadSimBuild(); err != nil {
+		return err
+	}
+
+	return aba.cancelAppCenterBuild()
+}
+
+//-----------------------------------------------------------------------------
+
+func (aba *AppCenterBuildAction) cancelAppCenterBuild() error {
+	ownerName := os.Getenv("SIM_APPCENTER_OWNER_NAME")
+	apiToken := os.Getenv("SIM_APPCENTER_API_TOKEN")
+	appName := os.Getenv("SIM_APPCENTER_APP_NAME")
+
+	if len(ownerName) == 0 || len(apiToken) == 0 || len(appName) == 0 {
+		return nil
+	}
+
+	buildID := os.Getenv("APPCENTER_BUILD_ID")
+
+	if len(buildID) == 0 {
+		return fmt.Errorf("Unable to cancel App Center build, error: %v not set", "APPCENTER_BUILD_ID")
+	}
+
+	aba.ioStreams.Printf("\nCancelling App Center build %q\n", buildID)
+
+	return api.CancelAppCenterBuild(
+		ownerName,
+		appName,
+		buildID,
+		apiToken,
+		aba.options.Upload.Verbose,
+		aba.ioStreams)
+}
+
+func (aba *AppCenterBuildAction) createSimBuild() error {
+	args := append([]string{}, aba.xcodeOptions...)
+
+	if strings.ToLower(filepath.Ext(aba.project)) == ".xcworkspace" {
+		args = append(args, "-workspace", aba.project)
+	} else {
+		args = append(args, "-project", aba.project)
+	}
+
+	args = append(args,
+		"-scheme", aba.scheme,
+		"-configuration", aba.configuration,
+		"-destination", "generic/platform=iOS Simulator",
+		"-derivedDataPath", aba.dataPath,
+		"clean", "build")
+
+	aba.ioStreams.Printf("\nBuilding scheme %q for iOS Simulator\n", aba.scheme)
+
+	task := lib.NewTask(detectXcodebuildPath(), args...)
+
+	task.Env = lib.CurrentEnvironment()
+	task.IOStreams = aba.ioStreams
+
+	if err := task.Execute(); err != nil {
+		return fmt.Errorf("Unable to build scheme %q, error: %v", aba.scheme, err)
+	}
+
+	return nil
+}
+
+func (aba *AppCenterBuildAction) makeUploadOptions() *UploadOptions {
+	options := aba.options.Upload
+
+	options.BuildPath = filepath.Join(
+		aba.dataPath,
+		"Build",
+		"Products",
+		aba.configuration+"-iphonesimulator",
+		aba.appName)
+
+	return &options
+}
+
+func (aba *AppCenterBuildAction) processSettings() error {
+	settings := map[string]*string{
+		"SIM_XCODE_APP_NAME":      &aba.appName,
+		"SIM_XCODE_CONFIGURATION": &aba.configuration,
+		"SIM_XCODE_PROJECT":       &aba.project,
+		"SIM_XCODE_SCHEME":        &aba.scheme}
+
+	var missing []string
+
+	for _, name := range lib.SortedKeys(settings) {
+		*settings[name] = os.Getenv(name)
+
+		if len(*settings[name]) == 0 {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("Missing required environment variable(s): %v", strings.Join(missing, ", "))
+	}
+
+	aba.xcodeOptions = strings.Fields(os.Getenv("SIM_XCODE_OPTIONS"))
+
+	return nil
+}
+
+func (aba *AppCenterBuildAction) uploadSimBuild() error {
+	return NewUploadAction(aba.makeUploadOptions(), aba.ioStreams).Perform()
+}
+
+//-----------------------------------------------------------------------------
+
+func detectXcodebuildPath() string {
+	if path := os.Getenv("WALDO_XCODEBUILD_PATH"); len(path) > 0 {
+		return path
+	}
+
+	return "xcodebuild"
+}
@@ -0,0 +1,192 @@
+package waldo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+)
+
+// writeTestXcodebuild writes a stand-in for xcodebuild that records its
+// arguments and “builds” the app into the derived data path.
+func writeTestXcodebuild(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in xcodebuild is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args.txt")
+	path := filepath.Join(dir, "xcodebuild")
+
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + argsPath + `"
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -configuration) configuration="$2"; shift ;;
+    -derivedDataPath) derived="$2"; shift ;;
+  esac
+  shift
+done
+app="$derived/Build/Products/$configuration-iphonesimulator/Sample.app"
+mkdir -p "$app" && printf '<plist/>' > "$app/Info.plist" && printf 'binary' > "$app/Sample"
+`
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, argsPath
+}
+
+func setAppCenterTestEnv(t *testing.T, xcodebuildPath string) {
+	t.Setenv("WALDO_XCODEBUILD_PATH", xcodebuildPath)
+	t.Setenv("SIM_XCODE_APP_NAME", "Sample.app")
+	t.Setenv("SIM_XCODE_CONFIGURATION", "Debug")
+	t.Setenv("SIM_XCODE_PROJECT", "Sample.xcworkspace")
+	t.Setenv("SIM_XCODE_SCHEME", "Sample/Staging")
+	t.Setenv("SIM_XCODE_OPTIONS", "-quiet")
+}
+
+func TestAppCenterBuildActionDirectUpload(t *testing.T) {
+	xcodebuildPath, argsPath := writeTestXcodebuild(t)
+
+	setAppCenterTestEnv(t, xcodebuildPath)
+
+	var (
+		mutex     sync.Mutex
+		completed bool
+		cancelled string
+		server    *httptest.Server
+	)
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/uploads":
+			json.NewEncoder(w).Encode(&api.CreateUploadResponse{
+				UploadID: "upl-1",
+				Parts:    []*api.UploadPart{{Number: 1, URL: server.URL + "/s3/part/1"}}})
+
+		case r.Method == "PUT" && r.URL.Path == "/s3/part/1":
+			io.Copy(io.Discard, r.Body)
+
+			w.Header().Set("ETag", `"abc123"`)
+
+		case r.Method == "POST" && r.URL.Path == "/uploads/upl-1/complete":
+			completed = true
+
+			json.NewEncoder(w).Encode(&api.CompleteUploadResponse{BuildID: "bld-1"})
+
+		case r.Method == "PATCH" && r.URL.Path == "/apps/acme/Sample/builds/17":
+			body, _ := io.ReadAll(r.Body)
+
+			cancelled = string(body)
+
+		default:
+			t.Errorf("unexpected request %v %v", r.Method, r.URL.Path)
+
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer server.Close()
+
+	t.Setenv("HOME", t.TempDir()) // keep the upload ledger out of the real home
+	t.Setenv("WALDO_API_CREATE_UPLOAD_ENDPOINT_OVERRIDE", server.URL+"/uploads")
+	t.Setenv("WALDO_APPCENTER_API_ENDPOINT_OVERRIDE", server.URL)
+	t.Setenv("SIM_APPCENTER_API_TOKEN", "ac-token")
+	t.Setenv("SIM_APPCENTER_APP_NAME", "Sample")
+	t.Setenv("SIM_APPCENTER_OWNER_NAME", "acme")
+	t.Setenv("APPCENTER_BUILD_ID", "17")
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	options := &AppCenterBuildOptions{
+		Upload: UploadOptions{
+			DirectUpload: true,
+			GitBranch:    "main",
+			GitCommit:    "0123456789abcdef0123456789abcdef01234567",
+			UploadToken:  "0123456789abcdef0123456789abcdef"}}
+
+	if err := NewAppCenterBuildAction(options, ioStreams).Perform(); err != nil {
+		t.Fatalf("Perform() error = %v", err)
+	}
+
+	args, err := os.ReadFile(argsPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"-quiet\n", "-workspace\nSample.xcworkspace\n", "-scheme\nSample/Staging\n", "-configuration\nDebug\n"} {
+		if !strings.Contains(string(args), want) {
+			t.Errorf("xcodebuild args = %q, want %q", args, want)
+		}
+	}
+
+	if !completed {
+		t.Errorf("upload not completed")
+	}
+
+	if cancelled != `{"status":"cancelling"}` {
+		t.Errorf("App Center build cancel body = %q", cancelled)
+	}
+}
+
+func TestAppCenterBuildActionUploadOptions(t *testing.T) {
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	tests := []struct {
+		name         string
+		directUpload bool
+	}{
+		{"Waldo Agent by default", false},
+		{"direct upload opt-in", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aba := NewAppCenterBuildAction(&AppCenterBuildOptions{Upload: UploadOptions{DirectUpload: tt.directUpload}}, ioStreams)
+
+			aba.appName = "Sample.app"
+			aba.configuration = "Release"
+			aba.dataPath = "/tmp/derived"
+
+			options := aba.makeUploadOptions()
+
+			if options.DirectUpload != tt.directUpload {
+				t.Errorf("DirectUpload = %v, want %v", options.DirectUpload, tt.directUpload)
+			}
+
+			if want := filepath.Join("/tmp/derived", "Build", "Products", "Release-iphonesimulator", "Sample.app"); options.BuildPath != want {
+				t.Errorf("BuildPath = %q, want %q", options.BuildPath, want)
+			}
+		})
+	}
+}
+
+func TestAppCenterBuildActionMissingSettings(t *testing.T) {
+	t.Setenv("SIM_XCODE_APP_NAME", "")
+	t.Setenv("SIM_XCODE_CONFIGURATION", "Debug")
+	t.Setenv("SIM_XCODE_PROJECT", "")
+	t.Setenv("SIM_XCODE_SCHEME", "Sample")
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	err := NewAppCenterBuildAction(&AppCenterBuildOptions{}, ioStreams).Perform()
+
+	if err == nil || !strings.Contains(err.Error(), "SIM_XCODE_APP_NAME, SIM_XCODE_PROJECT") {
+		t.Errorf("Perform() error = %v, want missing settings", err)
+	}
+}
@@ -0,0 +1,277 @@
+package artifact
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+)
+
+// Minimal decoder for the Android binary XML format used by the compiled
+// AndroidManifest.xml inside an APK. Only start elements and their attributes
+// are decoded.
+
+const (
+	axmlChunkResourceMap  = 0x0180
+	axmlChunkStartElement = 0x0102
+	axmlChunkStringPool   = 0x0001
+	axmlChunkXML          = 0x0003
+
+	axmlTypeIntBoolean = 0x12
+	axmlTypeIntDec     = 0x10
+	axmlTypeIntHex     = 0x11
+	axmlTypeReference  = 0x01
+	axmlTypeString     = 0x03
+
+	axmlUTF8Flag = 1 << 8
+)
+
+var axmlAttrResourceNames = map[uint32]string{
+	0x01010001: "label",
+	0x01010003: "name",
+	0x0101020c: "minSdkVersion",
+	0x01010270: "targetSdkVersion",
+	0x0101021b: "versionCode",
+	0x0101021c: "versionName"}
+
+//-----------------------------------------------------------------------------
+
+type axmlElement struct {
+	Name  string
+	Attrs map[string]string
+}
+
+//-----------------------------------------------------------------------------
+
+func decodeAXML(data []byte) ([]*axmlElement, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != axmlChunkXML {
+		return nil, errors.New("Invalid Android binary XML")
+	}
+
+	if int64(binary.LittleEndian.Uint32(data[4:])) > int64(len(data)) {
+		return nil, errors.New("Truncated Android binary XML")
+	}
+
+	var (
+		elements    []*axmlElement
+		resourceIDs []uint32
+		strings     []string
+	)
+
+	pos := int(binary.LittleEndian.Uint16(data[2:]))
+
+	for pos+8 <= len(data) {
+		chunkType := binary.LittleEndian.Uint16(data[pos:])
+		headerSize := int(binary.LittleEndian.Uint16(data[pos+2:]))
+		chunkSize := int(binary.LittleEndian.Uint32(data[pos+4:]))
+
+		if chunkSize < 8 || chunkSize > len(data)-pos {
+			return nil, errors.New("Truncated Android binary XML")
+		}
+
+		chunk := data[pos : pos+chunkSize]
+
+		switch chunkType {
+		case axmlChunkStringPool:
+			var err error
+
+			if strings, err = decodeAXMLStringPool(chunk); err != nil {
+				return nil, err
+			}
+
+		case axmlChunkResourceMap:
+			for off := headerSize; off+4 <= len(chunk); off += 4 {
+				resourceIDs = append(resourceIDs, binary.LittleEndian.Uint32(chunk[off:]))
+			}
+
+		case axmlChunkStartElement:
+			element, err := decodeAXMLStartElement(chunk, headerSize, strings, resourceIDs)
+
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, element)
+		}
+
+		pos += chunkSize
+	}
+
+	return elements, nil
+}
+
+func decodeAXMLStartElement(chunk []byte, headerSize int, strings []string, resourceIDs []uint32) (*axmlElement, error) {
+	if len(chunk) < headerSize+20 {
+		return nil, errors.New("Truncated Android binary XML element")
+	}
+
+	body := chunk[headerSize:]
+
+	element := &axmlElement{
+		Name:  lookupAXMLString(strings, binary.LittleEndian.Uint32(body[4:])),
+		Attrs: make(map[string]string)}
+
+	attrStart := int(binary.LittleEndian.Uint16(body[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(body[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(body[12:]))
+
+	if attrSize < 20 {
+		return nil, errors.New("Invalid Android binary XML attribute size")
+	}
+
+	for idx := 0; idx < attrCount; idx++ {
+		off := attrStart + idx*attrSize
+
+		if off+20 > len(body) {
+			return nil, errors.New("Truncated Android binary XML attribute")
+		}
+
+		attr := body[off : off+20]
+
+		nameIdx := binary.LittleEndian.Uint32(attr[4:])
+		rawIdx := binary.LittleEndian.Uint32(attr[8:])
+		dataType := attr[15]
+		dataValue := binary.LittleEndian.Uint32(attr[16:])
+
+		name := lookupAXMLString(strings, nameIdx)
+
+		if int(nameIdx) < len(resourceIDs) {
+			if resName, found := axmlAttrResourceNames[resourceIDs[nameIdx]]; found {
+				name = resName
+			}
+		}
+
+		if len(name) == 0 {
+			continue
+		}
+
+		var value string
+
+		switch dataType {
+		case axmlTypeString:
+			value = lookupAXMLString(strings, dataValue)
+
+		case axmlTypeIntDec:
+			value = strconv.FormatInt(int64(int32(dataValue)), 10)
+
+		case axmlTypeIntHex:
+			value = fmt.Sprintf("0x%08x", dataValue)
+
+		case axmlTypeIntBoolean:
+			value = strconv.FormatBool(dataValue != 0)
+
+		case axmlTypeReference:
+			value = fmt.Sprintf("@0x%08x", dataValue)
+
+		default:
+			value = lookupAXMLString(strings, rawIdx)
+		}
+
+		element.Attrs[name] = value
+	}
+
+	return element, nil
+}
+
+func decodeAXMLStringPool(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, errors.New("Truncated Android binary XML string pool")
+	}
+
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	flags := binary.LittleEndian.Uint32(chunk[16:])
+	stringsStart := int(binary.LittleEndian.Uint32(chunk[20:]))
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+
+	if headerSize > len(chunk) || count < 0 || count > (len(chunk)-headerSize)/4 || stringsStart < 0 || stringsStart > len(chunk) {
+		return nil, errors.New("Invalid Android binary XML string pool")
+	}
+
+	result := make([]string, count)
+
+	for idx := range result {
+		off := stringsStart + int(binary.LittleEndian.Uint32(chunk[headerSize+idx*4:]))
+
+		if off < 0 || off >= len(chunk) {
+			continue
+		}
+
+		if flags&axmlUTF8Flag != 0 {
+			result[idx] = decodeAXMLUTF8(chunk[off:])
+		} else {
+			result[idx] = decodeAXMLUTF16(chunk[off:])
+		}
+	}
+
+	return result, nil
+}
+
+func decodeAXMLUTF16(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+
+	count := int(binary.LittleEndian.Uint16(data))
+	pos := 2
+
+	if count&0x8000 != 0 && len(data) >= 4 {
+		count = (count&0x7fff)<<16 | int(binary.LittleEndian.Uint16(data[2:]))
+		pos = 4
+	}
+
+	if count > (len(data)-pos)/2 {
+		return ""
+	}
+
+	units := make([]uint16, count)
+
+	for idx := range units {
+		units[idx] = binary.LittleEndian.Uint16(data[pos+idx*2:])
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func decodeAXMLUTF8(data []byte) string {
+	pos := 0
+
+	// skip UTF-16 length, then read UTF-8 byte length:
+	for step := 0; step < 2; step++ {
+		if pos >= len(data) {
+			return ""
+		}
+
+		length := int(data[pos])
+
+		pos++
+
+		if length&0x80 != 0 {
+			if pos >= len(data) {
+				return ""
+			}
+
+			length = (length&0x7f)<<8 | int(data[pos])
+
+			pos++
+		}
+
+		if step == 1 {
+			if pos+length > len(data) {
+				return ""
+			}
+
+			return string(data[pos : pos+length])
+		}
+	}
+
+	return ""
+}
+
+func lookupAXMLString(strings []string, idx uint32) string {
+	if int(idx) < len(strings) && idx != 0xffffffff {
+		return strings[idx]
+	}
+
+	return ""
+}
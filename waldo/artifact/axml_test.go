@@ -0,0 +1,86 @@
+package artifact
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeAXMLFixtures(t *testing.T) {
+	want := []*axmlElement{
+		{Name: "manifest", Attrs: map[string]string{"package": "com.example.sample", "versionCode": "42", "versionName": "1.2.3"}},
+		{Name: "uses-sdk", Attrs: map[string]string{"minSdkVersion": "24"}},
+		{Name: "uses-permission", Attrs: map[string]string{"name": "android.permission.CAMERA"}},
+		{Name: "application", Attrs: map[string]string{"label": "Sample"}}}
+
+	for _, name := range []string{"AndroidManifest.utf16.axml", "AndroidManifest.utf8.axml"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeAXML(readTestData(t, name))
+
+			if err != nil {
+				t.Fatalf("decodeAXML() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decodeAXML() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeAXMLTruncated(t *testing.T) {
+	data := readTestData(t, "AndroidManifest.utf16.axml")
+
+	//
+	// Every truncation must fail cleanly (never panic):
+	//
+	for size := 0; size < len(data); size++ {
+		if _, err := decodeAXML(data[:size]); err == nil {
+			t.Errorf("decodeAXML() of %d bytes succeeded", size)
+		}
+	}
+}
+
+func TestDecodeAXMLMalicious(t *testing.T) {
+	valid := readTestData(t, "AndroidManifest.utf16.axml")
+
+	const (
+		poolOffset = 8 // string pool chunk follows the XML chunk header
+	)
+
+	tamper := func(offset int, value uint32) []byte {
+		data := append([]byte{}, valid...)
+
+		binary.LittleEndian.PutUint32(data[offset:], value)
+
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not binary XML", []byte("<manifest/>")},
+		{"chunk size overflow", tamper(poolOffset+4, 0xffffffff)},
+		{"chunk size too small", tamper(poolOffset+4, 4)},
+		{"string count overflow", tamper(poolOffset+8, 0xffffffff)},
+		{"strings start out of range", tamper(poolOffset+20, 0xfffffff0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := decodeAXML(tt.data); err == nil {
+				t.Errorf("decodeAXML() = %v, want error", got)
+			}
+		})
+	}
+
+	//
+	// Out-of-range string offsets and indexes decode to empty strings:
+	//
+	data := tamper(poolOffset+28, 0x7ffffff0)
+
+	if _, err := decodeAXML(data); err != nil {
+		t.Errorf("decodeAXML() with bad string offset error = %v", err)
+	}
+}
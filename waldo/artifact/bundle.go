@@ -0,0 +1,179 @@
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+const (
+	debugKeyAlias    = "androiddebugkey"
+	debugKeyPassword = "android"
+)
+
+//-----------------------------------------------------------------------------
+
+func (u *Unpacker) convertBundle(aabPath string) (string, error) {
+	if len(u.workingPath) == 0 {
+		workingPath, err := os.MkdirTemp("", "WaldoGoCLI-unpack-")
+
+		if err != nil {
+			return "", err
+		}
+
+		u.workingPath = workingPath
+	}
+
+	bundletool, err := u.findBundletool()
+
+	if err != nil {
+		return "", err
+	}
+
+	keystorePath, err := u.findDebugKeystore()
+
+	if err != nil {
+		return "", err
+	}
+
+	u.ioStreams.Printf("\nConverting %q to universal APK\n", trimBase(aabPath))
+
+	apksPath := filepath.Join(u.workingPath, "universal.apks")
+
+	args := append(append([]string{}, bundletool[1:]...),
+		"build-apks",
+		"--bundle="+aabPath,
+		"--output="+apksPath,
+		"--mode=universal",
+		"--overwrite",
+		"--ks="+keystorePath,
+		"--ks-pass=pass:"+debugKeyPassword,
+		"--ks-key-alias="+debugKeyAlias,
+		"--key-pass=pass:"+debugKeyPassword)
+
+	if err := u.runTool("bundletool", bundletool[0], args...); err != nil {
+		return "", err
+	}
+
+	apksDirPath := filepath.Join(u.workingPath, "universal")
+
+	if err := lib.ExtractZip(apksPath, apksDirPath, nil); err != nil {
+		return "", fmt.Errorf("Unable to extract universal APK, error: %v", err)
+	}
+
+	apkPath := filepath.Join(apksDirPath, "universal.apk")
+
+	if !lib.IsRegularFile(apkPath) {
+		return "", errors.New("Unable to extract universal APK, error: no universal.apk produced by bundletool")
+	}
+
+	namedPath := filepath.Join(u.workingPath, strings.TrimSuffix(trimBase(aabPath), filepath.Ext(aabPath))+".apk")
+
+	if err := os.Rename(apkPath, namedPath); err != nil {
+		return "", err
+	}
+
+	return namedPath, nil
+}
+
+func (u *Unpacker) findBundletool() ([]string, error) {
+	toolPath := u.bundletoolPath
+
+	if len(toolPath) == 0 {
+		var err error
+
+		toolPath, err = exec.LookPath("bundletool")
+
+		if err != nil {
+			return nil, errors.New("Unable to find bundletool -- set WALDO_BUNDLETOOL_PATH or bundletool_path in the Waldo configuration")
+		}
+	}
+
+	if !lib.IsRegularFile(toolPath) {
+		return nil, fmt.Errorf("Unable to find bundletool at %q", toolPath)
+	}
+
+	if strings.HasSuffix(strings.ToLower(toolPath), ".jar") {
+		return []string{findJavaTool("java"), "-jar", toolPath}, nil
+	}
+
+	return []string{toolPath}, nil
+}
+
+func (u *Unpacker) findDebugKeystore() (string, error) {
+	if homePath, err := os.UserHomeDir(); err == nil {
+		keystorePath := filepath.Join(homePath, ".android", "debug.keystore")
+
+		if lib.IsRegularFile(keystorePath) {
+			return keystorePath, nil
+		}
+	}
+
+	keystorePath := filepath.Join(u.workingPath, "debug.keystore")
+
+	err := u.runTool(
+		"keytool",
+		findJavaTool("keytool"),
+		"-genkeypair",
+		"-keystore", keystorePath,
+		"-storepass", debugKeyPassword,
+		"-alias", debugKeyAlias,
+		"-keypass", debugKeyPassword,
+		"-keyalg", "RSA",
+		"-keysize", "2048",
+		"-validity", "10000",
+		"-dname", "CN=Android Debug,O=Android,C=US")
+
+	if err != nil {
+		return "", err
+	}
+
+	return keystorePath, nil
+}
+
+func (u *Unpacker) runTool(label, name string, args ...string) error {
+	task := lib.NewTask(name, args...)
+
+	task.Env = lib.CurrentEnvironment()
+
+	stdout, stderr, err := task.Run()
+
+	if u.verbose {
+		if len(stdout) > 0 {
+			u.ioStreams.Printf("\n--- %v output ---\n%v\n", label, stdout)
+		}
+
+		if len(stderr) > 0 {
+			u.ioStreams.Printf("\n--- %v errors ---\n%v\n", label, stderr)
+		}
+	}
+
+	if err != nil {
+		if len(stderr) > 0 {
+			return fmt.Errorf("Unable to run %v, error: %v, details: %v", label, err, stderr)
+		}
+
+		return fmt.Errorf("Unable to run %v, error: %v", label, err)
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func findJavaTool(name string) string {
+	if javaHome := os.Getenv("JAVA_HOME"); len(javaHome) > 0 {
+		toolPath := filepath.Join(javaHome, "bin", name)
+
+		if lib.IsRegularFile(toolPath) || lib.IsRegularFile(toolPath+".exe") {
+			return toolPath
+		}
+	}
+
+	return name
+}
@@ -0,0 +1,224 @@
+package artifact
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// writeTestBundletool writes a stand-in for bundletool that records its
+// arguments and “builds” the given .apks file, provided the keystore exists.
+func writeTestBundletool(t *testing.T, apksPath string) (string, string) {
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args.txt")
+	path := filepath.Join(dir, "bundletool")
+
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + argsPath + `"
+for arg in "$@"; do
+  case "$arg" in
+    --output=*) output="${arg#--output=}" ;;
+    --ks=*) ks="${arg#--ks=}" ;;
+  esac
+done
+[ -f "$ks" ] || { echo "no keystore: $ks" >&2; exit 3; }
+cp "` + apksPath + `" "$output"
+`
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, argsPath
+}
+
+// writeTestKeytool writes a stand-in for keytool into a fake JAVA_HOME that
+// records its arguments and “generates” the keystore.
+func writeTestKeytool(t *testing.T) (string, string) {
+	javaHome := t.TempDir()
+	argsPath := filepath.Join(javaHome, "args.txt")
+	path := filepath.Join(javaHome, "bin", "keytool")
+
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + argsPath + `"
+while [ $# -gt 0 ]; do
+  case "$1" in
+    -keystore) keystore="$2"; shift ;;
+  esac
+  shift
+done
+printf 'keystore' > "$keystore"
+`
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return javaHome, argsPath
+}
+
+func TestUnpackerConvertBundle(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in bundletool and keytool are shell scripts")
+	}
+
+	apk, err := os.ReadFile(writeTestApk(t, map[string][]byte{"AndroidManifest.xml": []byte("manifest")}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		apksEntries [][2]string
+		hasKeystore bool
+		wantKeytool bool
+		wantErr     string
+	}{
+		{
+			name:        "generates debug keystore",
+			apksEntries: [][2]string{{"universal.apk", string(apk)}, {"toc.pb", "toc"}},
+			wantKeytool: true},
+		{
+			name:        "uses existing debug keystore",
+			apksEntries: [][2]string{{"universal.apk", string(apk)}},
+			hasKeystore: true},
+		{
+			name:        "no universal.apk",
+			apksEntries: [][2]string{{"standalones/standalone-arm64_v8a.apk", string(apk)}},
+			hasKeystore: true,
+			wantErr:     "no universal.apk produced by bundletool"},
+		{
+			name:        "no AndroidManifest.xml",
+			apksEntries: [][2]string{{"universal.apk", "not a zip"}},
+			hasKeystore: true,
+			wantErr:     "no AndroidManifest.xml found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			homePath := t.TempDir()
+			keystorePath := filepath.Join(homePath, ".android", "debug.keystore")
+
+			t.Setenv("HOME", homePath)
+			t.Setenv("TMPDIR", t.TempDir())
+
+			if tt.hasKeystore {
+				if err := os.MkdirAll(filepath.Dir(keystorePath), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(keystorePath, []byte("keystore"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			javaHome, keytoolArgsPath := writeTestKeytool(t)
+
+			t.Setenv("JAVA_HOME", javaHome)
+
+			bundletoolPath, bundletoolArgsPath := writeTestBundletool(t, writeTestZip(t, tt.apksEntries))
+
+			aabPath := filepath.Join(t.TempDir(), "Sample.aab")
+
+			if err := os.WriteFile(aabPath, []byte("bundle"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+			unpacker := NewUnpacker(aabPath, bundletoolPath, false, ioStreams)
+
+			defer unpacker.Cleanup()
+
+			apkPath, err := unpacker.Unpack()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unpack() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+
+			if filepath.Base(apkPath) != "Sample.apk" || !hasZipEntry(apkPath, "AndroidManifest.xml") {
+				t.Errorf("Unpack() = %q", apkPath)
+			}
+
+			rawArgs, err := os.ReadFile(bundletoolArgsPath)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			args := strings.Split(strings.TrimSpace(string(rawArgs)), "\n")
+
+			wantKeystorePath := keystorePath
+
+			if tt.wantKeytool {
+				wantKeystorePath = filepath.Join(unpacker.workingPath, "debug.keystore")
+			}
+
+			for _, want := range []string{"build-apks", "--bundle=" + aabPath, "--mode=universal", "--ks=" + wantKeystorePath, "--ks-key-alias=androiddebugkey"} {
+				if !slices.Contains(args, want) {
+					t.Errorf("bundletool args = %v, want %q", args, want)
+				}
+			}
+
+			if ranKeytool := lib.IsRegularFile(keytoolArgsPath); ranKeytool != tt.wantKeytool {
+				t.Errorf("ran keytool = %v, want %v", ranKeytool, tt.wantKeytool)
+			}
+
+			if lib.IsRegularFile(keystorePath) != tt.hasKeystore {
+				t.Errorf("keytool wrote to %q", keystorePath)
+			}
+		})
+	}
+}
+
+func TestUnpackerConvertBundleFailure(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in keytool is a shell script")
+	}
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMPDIR", t.TempDir())
+
+	javaHome, _ := writeTestKeytool(t)
+
+	t.Setenv("JAVA_HOME", javaHome)
+
+	bundletoolPath := filepath.Join(t.TempDir(), "bundletool")
+
+	if err := os.WriteFile(bundletoolPath, []byte("#!/bin/sh\necho 'bad bundle' >&2\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	aabPath := filepath.Join(t.TempDir(), "Sample.aab")
+
+	if err := os.WriteFile(aabPath, []byte("bundle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+	unpacker := NewUnpacker(aabPath, bundletoolPath, false, ioStreams)
+
+	defer unpacker.Cleanup()
+
+	if _, err := unpacker.Unpack(); err == nil || !strings.Contains(err.Error(), "Unable to run bundletool") || !strings.Contains(err.Error(), "bad bundle") {
+		t.Errorf("Unpack() error = %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package artifact
+
+import (
+	"cmp"
+	"slices"
+)
+
+type Delta struct {
+	Old                 *Info          `json:"old"`
+	New                 *Info          `json:"new"`
+	MetadataChanges     []*FieldChange `json:"metadataChanges"`
+	AddedFiles          []*FileChange  `json:"addedFiles"`
+	RemovedFiles        []*FileChange  `json:"removedFiles"`
+	ResizedFiles        []*FileChange  `json:"resizedFiles"`
+	AddedFrameworks     []string       `json:"addedFrameworks"`
+	RemovedFrameworks   []string       `json:"removedFrameworks"`
+	AddedPermissions    []string       `json:"addedPermissions"`
+	RemovedPermissions  []string       `json:"removedPermissions"`
+	AddedEntitlements   []string       `json:"addedEntitlements"`
+	RemovedEntitlements []string       `json:"removedEntitlements"`
+	SizeChange          int64          `json:"sizeChange"`
+}
+
+type FieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+type FileChange struct {
+	Path    string `json:"path"`
+	OldSize int64  `json:"oldSize"`
+	NewSize int64  `json:"newSize"`
+}
+
+//-----------------------------------------------------------------------------
+
+func Diff(oldInfo, newInfo *Info) *Delta {
+	delta := &Delta{
+		Old:        oldInfo,
+		New:        newInfo,
+		SizeChange: newInfo.TotalSize - oldInfo.TotalSize}
+
+	delta.diffMetadata()
+	delta.diffFiles()
+
+	delta.AddedFrameworks, delta.RemovedFrameworks = diffStrings(oldInfo.Frameworks, newInfo.Frameworks)
+	delta.AddedPermissions, delta.RemovedPermissions = diffStrings(oldInfo.Permissions, newInfo.Permissions)
+	delta.AddedEntitlements, delta.RemovedEntitlements = diffStrings(oldInfo.Entitlements, newInfo.Entitlements)
+
+	return delta
+}
+
+//-----------------------------------------------------------------------------
+
+func (d *Delta) HasChanges() bool {
+	return len(d.MetadataChanges) > 0 ||
+		len(d.AddedFiles) > 0 ||
+		len(d.RemovedFiles) > 0 ||
+		len(d.ResizedFiles) > 0 ||
+		len(d.AddedFrameworks) > 0 ||
+		len(d.RemovedFrameworks) > 0 ||
+		len(d.AddedPermissions) > 0 ||
+		len(d.RemovedPermissions) > 0 ||
+		len(d.AddedEntitlements) > 0 ||
+		len(d.RemovedEntitlements) > 0 ||
+		d.SizeChange != 0
+}
+
+//-----------------------------------------------------------------------------
+
+func (d *Delta) diffFiles() {
+	for path, oldSize := range d.Old.Files {
+		newSize, found := d.New.Files[path]
+
+		if !found {
+			d.RemovedFiles = append(d.RemovedFiles, &FileChange{Path: path, OldSize: oldSize})
+		} else if newSize != oldSize {
+			d.ResizedFiles = append(d.ResizedFiles, &FileChange{Path: path, OldSize: oldSize, NewSize: newSize})
+		}
+	}
+
+	for path, newSize := range d.New.Files {
+		if _, found := d.Old.Files[path]; !found {
+			d.AddedFiles = append(d.AddedFiles, &FileChange{Path: path, NewSize: newSize})
+		}
+	}
+
+	sortFileChanges(d.AddedFiles)
+	sortFileChanges(d.RemovedFiles)
+	sortFileChanges(d.ResizedFiles)
+}
+
+func (d *Delta) diffMetadata() {
+	fields := []struct {
+		name     string
+		oldValue string
+		newValue string
+	}{
+		{"platform", string(d.Old.Platform), string(d.New.Platform)},
+		{"bundleId", d.Old.BundleID, d.New.BundleID},
+		{"displayName", d.Old.DisplayName, d.New.DisplayName},
+		{"version", d.Old.Version, d.New.Version},
+		{"buildVersion", d.Old.BuildVersion, d.New.BuildVersion},
+		{"minOSVersion", d.Old.MinOSVersion, d.New.MinOSVersion}}
+
+	for _, field := range fields {
+		if field.oldValue != field.newValue {
+			d.MetadataChanges = append(d.MetadataChanges, &FieldChange{
+				Field:    field.name,
+				OldValue: field.oldValue,
+				NewValue: field.newValue})
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func diffStrings(oldValues, newValues []string) ([]string, []string) {
+	var added, removed []string
+
+	for _, value := range newValues {
+		if !slices.Contains(oldValues, value) {
+			added = append(added, value)
+		}
+	}
+
+	for _, value := range oldValues {
+		if !slices.Contains(newValues, value) {
+			removed = append(removed, value)
+		}
+	}
+
+	return added, removed
+}
+
+func sortFileChanges(changes []*FileChange) {
+	slices.SortFunc(changes, func(a, b *FileChange) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+}
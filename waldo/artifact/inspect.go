@@ -0,0 +1,331 @@
+package artifact
+
+import (
+	"archive/zip"
+	"debug/macho"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+type Info struct {
+	Path         string           `json:"path"`
+	Platform     lib.Platform     `json:"platform"`
+	BundleID     string           `json:"bundleId,omitempty"`
+	DisplayName  string           `json:"displayName,omitempty"`
+	Version      string           `json:"version,omitempty"`
+	BuildVersion string           `json:"buildVersion,omitempty"`
+	MinOSVersion string           `json:"minOSVersion,omitempty"`
+	TotalSize    int64            `json:"totalSize"` // sum of Files (uncompressed)
+	Files        map[string]int64 `json:"-"`         // uncompressed size by path
+	Frameworks   []string         `json:"frameworks,omitempty"`
+	Permissions  []string         `json:"permissions,omitempty"`
+	Entitlements []string         `json:"entitlements,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func Inspect(buildPath string) (*Info, error) {
+	if err := Validate(buildPath); err != nil {
+		return nil, err
+	}
+
+	var (
+		info *Info
+		err  error
+	)
+
+	switch DetectKind(buildPath) {
+	case KindApk:
+		info, err = inspectApk(buildPath)
+
+	default: // KindApp
+		info, err = inspectApp(buildPath)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to inspect build, error: %v, path: %q", err, buildPath)
+	}
+
+	slices.Sort(info.Entitlements)
+	slices.Sort(info.Frameworks)
+	slices.Sort(info.Permissions)
+
+	return info, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func inspectApk(apkPath string) (*Info, error) {
+	zr, err := zip.OpenReader(apkPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer zr.Close()
+
+	info := &Info{
+		Path:     apkPath,
+		Platform: lib.PlatformAndroid,
+		Files:    make(map[string]int64)}
+
+	for _, zf := range zr.File {
+		if zf.Mode().IsDir() {
+			continue
+		}
+
+		//
+		// Use the uncompressed size so that the total size is comparable with
+		// that of an app bundle (whose files are not compressed):
+		//
+		info.Files[zf.Name] = int64(zf.UncompressedSize64)
+		info.TotalSize += int64(zf.UncompressedSize64)
+
+		if strings.HasPrefix(zf.Name, "lib/") && strings.HasSuffix(zf.Name, ".so") {
+			info.Frameworks = append(info.Frameworks, zf.Name)
+		}
+
+		if zf.Name == "AndroidManifest.xml" {
+			if err := inspectManifest(zf, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return info, nil
+}
+
+func inspectApp(appPath string) (*Info, error) {
+	info := &Info{
+		Path:     appPath,
+		Platform: lib.PlatformIos,
+		Files:    make(map[string]int64)}
+
+	err := filepath.WalkDir(appPath, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath := filepath.ToSlash(lib.MakeRelative(path, appPath))
+
+		if de.IsDir() {
+			if isFrameworkPath(relPath) {
+				info.Frameworks = append(info.Frameworks, relPath)
+			}
+
+			return nil
+		}
+
+		fi, err := de.Info()
+
+		if err != nil {
+			return err
+		}
+
+		size := int64(0)
+
+		if fi.Mode().IsRegular() {
+			size = fi.Size()
+		}
+
+		info.Files[relPath] = size
+		info.TotalSize += size
+
+		if isFrameworkPath(relPath) {
+			info.Frameworks = append(info.Frameworks, relPath)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	executable, err := inspectInfoPlist(filepath.Join(appPath, "Info.plist"), info)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.Entitlements = inspectEntitlements(appPath, executable)
+
+	return info, nil
+}
+
+func inspectEntitlements(appPath, executable string) []string {
+	var data []byte
+
+	if xcentPath := filepath.Join(appPath, "archived-expanded-entitlements.xcent"); lib.IsRegularFile(xcentPath) {
+		data, _ = os.ReadFile(xcentPath)
+	} else if len(executable) > 0 {
+		data = readEntitlementsSection(filepath.Join(appPath, executable))
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	value, err := decodePlist(data)
+
+	if err != nil {
+		return nil
+	}
+
+	dict, _ := value.(map[string]any)
+
+	var result []string
+
+	for key := range dict {
+		result = append(result, key)
+	}
+
+	return result
+}
+
+func inspectInfoPlist(plistPath string, info *Info) (string, error) {
+	data, err := os.ReadFile(plistPath)
+
+	if err != nil {
+		return "", err
+	}
+
+	value, err := decodePlist(data)
+
+	if err != nil {
+		return "", err
+	}
+
+	dict, ok := value.(map[string]any)
+
+	if !ok {
+		return "", fmt.Errorf("Unexpected Info.plist contents")
+	}
+
+	info.BundleID = plistString(dict, "CFBundleIdentifier")
+	info.BuildVersion = plistString(dict, "CFBundleVersion")
+	info.DisplayName = plistString(dict, "CFBundleDisplayName")
+	info.MinOSVersion = plistString(dict, "MinimumOSVersion")
+	info.Version = plistString(dict, "CFBundleShortVersionString")
+
+	if len(info.DisplayName) == 0 {
+		info.DisplayName = plistString(dict, "CFBundleName")
+	}
+
+	return plistString(dict, "CFBundleExecutable"), nil
+}
+
+func inspectManifest(zf *zip.File, info *Info) error {
+	rc, err := zf.Open()
+
+	if err != nil {
+		return err
+	}
+
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+
+	if err != nil {
+		return err
+	}
+
+	elements, err := decodeAXML(data)
+
+	if err != nil {
+		return err
+	}
+
+	for _, element := range elements {
+		switch element.Name {
+		case "manifest":
+			info.BundleID = element.Attrs["package"]
+			info.BuildVersion = element.Attrs["versionCode"]
+			info.Version = element.Attrs["versionName"]
+
+		case "uses-permission", "uses-permission-sdk-23":
+			if name := element.Attrs["name"]; len(name) > 0 {
+				info.Permissions = append(info.Permissions, name)
+			}
+
+		case "uses-sdk":
+			info.MinOSVersion = element.Attrs["minSdkVersion"]
+
+		case "application":
+			if label := element.Attrs["label"]; len(label) > 0 && !strings.HasPrefix(label, "@") {
+				info.DisplayName = label
+			}
+		}
+	}
+
+	return nil
+}
+
+func isFrameworkPath(relPath string) bool {
+	if !strings.HasPrefix(relPath, "Frameworks/") || strings.Count(relPath, "/") != 1 {
+		return false
+	}
+
+	switch filepath.Ext(relPath) {
+	case ".dylib", ".framework":
+		return true
+
+	default:
+		return false
+	}
+}
+
+func plistString(dict map[string]any, key string) string {
+	if value, found := dict[key]; found && value != nil {
+		return fmt.Sprint(value)
+	}
+
+	return ""
+}
+
+func readEntitlementsSection(execPath string) []byte {
+	if ff, err := macho.OpenFat(execPath); err == nil {
+		defer ff.Close()
+
+		for _, arch := range ff.Arches {
+			if data := readMachOSection(arch.File); len(data) > 0 {
+				return data
+			}
+		}
+
+		return nil
+	}
+
+	file, err := macho.Open(execPath)
+
+	if err != nil {
+		return nil
+	}
+
+	defer file.Close()
+
+	return readMachOSection(file)
+}
+
+func readMachOSection(file *macho.File) []byte {
+	section := file.Section("__entitlements")
+
+	if section == nil {
+		return nil
+	}
+
+	data, err := section.Data()
+
+	if err != nil {
+		return nil
+	}
+
+	return data
+}
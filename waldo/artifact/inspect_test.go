@@ -0,0 +1,132 @@
+package artifact
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+func writeTestApk(t *testing.T, files map[string][]byte) string {
+	apkPath := filepath.Join(t.TempDir(), "Sample.apk")
+
+	file, err := os.Create(apkPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(file)
+
+	for name, contents := range files {
+		w, err := zw.Create(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write(contents)
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file.Close()
+
+	return apkPath
+}
+
+func writeTestApp(t *testing.T, files map[string][]byte) string {
+	appPath := filepath.Join(t.TempDir(), "Sample.app")
+
+	for name, contents := range files {
+		path := filepath.Join(appPath, name)
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, contents, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return appPath
+}
+
+func TestInspectApk(t *testing.T) {
+	manifest := readTestData(t, "AndroidManifest.utf16.axml")
+	library := []byte(strings.Repeat("x", 4096)) // compresses well
+
+	info, err := Inspect(writeTestApk(t, map[string][]byte{
+		"AndroidManifest.xml":           manifest,
+		"lib/arm64-v8a/libfoo.so":       library,
+		"res/drawable/icon.png":         []byte("png"),
+		"META-INF/MANIFEST.MF":          []byte("Manifest-Version: 1.0\n"),
+		"assets/config/extra.json":      []byte("{}"),
+		"lib/x86_64/libfoo.so":          library,
+		"classes.dex":                   []byte("dex"),
+		"resources.arsc":                []byte("arsc"),
+		"kotlin/kotlin.kotlin_builtins": []byte("kt")}))
+
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+
+	if info.Platform != lib.PlatformAndroid || info.BundleID != "com.example.sample" || info.BuildVersion != "42" ||
+		info.Version != "1.2.3" || info.MinOSVersion != "24" || info.DisplayName != "Sample" {
+		t.Errorf("Inspect() = %+v", info)
+	}
+
+	if want := []string{"android.permission.CAMERA"}; !reflect.DeepEqual(info.Permissions, want) {
+		t.Errorf("Permissions = %v, want %v", info.Permissions, want)
+	}
+
+	if want := []string{"lib/arm64-v8a/libfoo.so", "lib/x86_64/libfoo.so"}; !reflect.DeepEqual(info.Frameworks, want) {
+		t.Errorf("Frameworks = %v, want %v", info.Frameworks, want)
+	}
+
+	assertTotalSize(t, info)
+}
+
+func TestInspectApp(t *testing.T) {
+	info, err := Inspect(writeTestApp(t, map[string][]byte{
+		"Info.plist":                          readTestData(t, "Info.binary.plist"),
+		"Sample":                              []byte("not a Mach-O binary"),
+		"Frameworks/Foo.framework/Foo":        []byte("framework"),
+		"Frameworks/Foo.framework/Info.plist": []byte("<plist/>")}))
+
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+
+	if info.Platform != lib.PlatformIos || info.BundleID != "com.example.sample" || info.BuildVersion != "42" ||
+		info.Version != "1.2.3" || info.MinOSVersion != "15.0" || info.DisplayName != "Sämple ✓" {
+		t.Errorf("Inspect() = %+v", info)
+	}
+
+	if want := []string{"Frameworks/Foo.framework"}; !reflect.DeepEqual(info.Frameworks, want) {
+		t.Errorf("Frameworks = %v, want %v", info.Frameworks, want)
+	}
+
+	assertTotalSize(t, info)
+}
+
+// assertTotalSize checks that the total size is the sum of the uncompressed
+// file sizes, whatever the kind of build.
+func assertTotalSize(t *testing.T, info *Info) {
+	var sum int64
+
+	for _, size := range info.Files {
+		sum += size
+	}
+
+	if info.TotalSize != sum || sum == 0 {
+		t.Errorf("TotalSize = %d, want sum of file sizes %d", info.TotalSize, sum)
+	}
+}
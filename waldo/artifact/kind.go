@@ -0,0 +1,128 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+type Kind string
+
+const (
+	KindAab       = "aab"
+	KindApk       = "apk"
+	KindApp       = "app"
+	KindTarGz     = "tar.gz"
+	KindUnknown   = "unknown"
+	KindXcarchive = "xcarchive"
+	KindZip       = "zip"
+)
+
+//-----------------------------------------------------------------------------
+
+func DetectKind(path string) Kind {
+	lowerPath := strings.ToLower(strings.TrimRight(path, `/\`))
+
+	switch {
+	case strings.HasSuffix(lowerPath, ".aab"):
+		return KindAab
+
+	case strings.HasSuffix(lowerPath, ".apk"):
+		return KindApk
+
+	case strings.HasSuffix(lowerPath, ".app"):
+		return KindApp
+
+	case strings.HasSuffix(lowerPath, ".tar.gz"), strings.HasSuffix(lowerPath, ".tgz"):
+		return KindTarGz
+
+	case strings.HasSuffix(lowerPath, ".xcarchive"):
+		return KindXcarchive
+
+	case strings.HasSuffix(lowerPath, ".zip"):
+		return KindZip
+	}
+
+	if lib.IsRegularFile(path) {
+		return sniffKind(path)
+	}
+
+	return KindUnknown
+}
+
+func (k Kind) Platform() lib.Platform {
+	switch k {
+	case KindAab, KindApk:
+		return lib.PlatformAndroid
+
+	case KindApp, KindXcarchive:
+		return lib.PlatformIos
+
+	default:
+		return lib.PlatformUnknown
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func hasZipEntry(path, name string) bool {
+	zr, err := zip.OpenReader(path)
+
+	if err != nil {
+		return false
+	}
+
+	defer zr.Close()
+
+	for _, zf := range zr.File {
+		if zf.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sniffKind(path string) Kind {
+	file, err := os.Open(path)
+
+	if err != nil {
+		return KindUnknown
+	}
+
+	defer file.Close()
+
+	magic := make([]byte, 4)
+
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return KindUnknown
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte("PK\x03\x04")):
+		if hasZipEntry(path, "AndroidManifest.xml") {
+			return KindApk
+		}
+
+		if hasZipEntry(path, "base/manifest/AndroidManifest.xml") {
+			return KindAab
+		}
+
+		return KindZip
+
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		return KindTarGz
+
+	default:
+		return KindUnknown
+	}
+}
+
+func trimBase(path string) string {
+	return filepath.Base(strings.TrimRight(path, `/\`))
+}
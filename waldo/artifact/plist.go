@@ -0,0 +1,481 @@
+package artifact
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Minimal property list decoder supporting both the binary (bplist00) and XML
+// formats. Dictionaries decode to map[string]any, arrays to []any, integers
+// to int64, reals to float64, and dates to their textual (XML) or numeric
+// (binary) form.
+
+func decodePlist(data []byte) (any, error) {
+	if bytes.HasPrefix(data, []byte("bplist00")) {
+		return decodeBinaryPlist(data)
+	}
+
+	return decodeXMLPlist(data)
+}
+
+//-----------------------------------------------------------------------------
+
+type binaryPlist struct {
+	data        []byte
+	objectRefSz int
+	offsets     []uint64
+}
+
+//-----------------------------------------------------------------------------
+
+func decodeBinaryPlist(data []byte) (any, error) {
+	if len(data) < 8+32 {
+		return nil, errors.New("Truncated binary plist")
+	}
+
+	trailer := data[len(data)-32:]
+
+	offsetIntSz := int(trailer[6])
+	objectRefSz := int(trailer[7])
+	numObjects := binary.BigEndian.Uint64(trailer[8:16])
+	topObject := binary.BigEndian.Uint64(trailer[16:24])
+	offsetTableOffset := binary.BigEndian.Uint64(trailer[24:32])
+
+	if offsetIntSz < 1 || offsetIntSz > 8 || objectRefSz < 1 || objectRefSz > 8 {
+		return nil, errors.New("Invalid binary plist trailer")
+	}
+
+	//
+	// Compare without multiplying or adding values from the trailer, which
+	// could overflow:
+	//
+	if offsetTableOffset > uint64(len(data)) || numObjects > (uint64(len(data))-offsetTableOffset)/uint64(offsetIntSz) {
+		return nil, errors.New("Invalid binary plist offset table")
+	}
+
+	bp := &binaryPlist{
+		data:        data,
+		objectRefSz: objectRefSz,
+		offsets:     make([]uint64, numObjects)}
+
+	for idx := range bp.offsets {
+		start := offsetTableOffset + uint64(idx*offsetIntSz)
+
+		bp.offsets[idx] = readUint(data[start : start+uint64(offsetIntSz)])
+	}
+
+	return bp.object(topObject, 0)
+}
+
+//-----------------------------------------------------------------------------
+
+func (bp *binaryPlist) length(marker byte, pos uint64) (uint64, uint64, error) {
+	count := uint64(marker & 0x0f)
+
+	if count != 0x0f {
+		return count, pos, nil
+	}
+
+	if pos >= uint64(len(bp.data)) || bp.data[pos]&0xf0 != 0x10 {
+		return 0, 0, errors.New("Invalid binary plist length")
+	}
+
+	size := uint64(1) << (bp.data[pos] & 0x0f)
+
+	if err := bp.check(pos+1, size); err != nil {
+		return 0, 0, err
+	}
+
+	return readUint(bp.data[pos+1 : pos+1+size]), pos + 1 + size, nil
+}
+
+func (bp *binaryPlist) object(ref uint64, depth int) (any, error) {
+	if ref >= uint64(len(bp.offsets)) || depth > 64 {
+		return nil, errors.New("Invalid binary plist object reference")
+	}
+
+	pos := bp.offsets[ref]
+
+	if pos >= uint64(len(bp.data)) {
+		return nil, errors.New("Invalid binary plist object offset")
+	}
+
+	marker := bp.data[pos]
+
+	pos++
+
+	switch marker & 0xf0 {
+	case 0x00:
+		switch marker {
+		case 0x08:
+			return false, nil
+
+		case 0x09:
+			return true, nil
+
+		default:
+			return nil, nil
+		}
+
+	case 0x10:
+		size := uint64(1) << (marker & 0x0f)
+
+		if err := bp.check(pos, size); err != nil {
+			return nil, err
+		}
+
+		return int64(readUint(bp.data[pos : pos+size])), nil
+
+	case 0x20, 0x30:
+		size := uint64(1) << (marker & 0x0f)
+
+		if marker&0xf0 == 0x30 {
+			size = 8
+		}
+
+		if err := bp.check(pos, size); err != nil {
+			return nil, err
+		}
+
+		switch size {
+		case 4:
+			return float64(math.Float32frombits(uint32(readUint(bp.data[pos : pos+4])))), nil
+
+		case 8:
+			return math.Float64frombits(readUint(bp.data[pos : pos+8])), nil
+
+		default:
+			return nil, errors.New("Invalid binary plist real")
+		}
+
+	case 0x40:
+		count, pos, err := bp.length(marker, pos)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := bp.check(pos, count); err != nil {
+			return nil, err
+		}
+
+		return append([]byte{}, bp.data[pos:pos+count]...), nil
+
+	case 0x50:
+		count, pos, err := bp.length(marker, pos)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := bp.check(pos, count); err != nil {
+			return nil, err
+		}
+
+		return string(bp.data[pos : pos+count]), nil
+
+	case 0x60:
+		count, pos, err := bp.length(marker, pos)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err := bp.checkArray(pos, count, 2); err != nil {
+			return nil, err
+		}
+
+		units := make([]uint16, count)
+
+		for idx := range units {
+			units[idx] = binary.BigEndian.Uint16(bp.data[pos+uint64(idx*2):])
+		}
+
+		return string(utf16.Decode(units)), nil
+
+	case 0x80:
+		size := uint64(marker&0x0f) + 1
+
+		if err := bp.check(pos, size); err != nil {
+			return nil, err
+		}
+
+		return int64(readUint(bp.data[pos : pos+size])), nil
+
+	case 0xa0:
+		count, pos, err := bp.length(marker, pos)
+
+		if err != nil {
+			return nil, err
+		}
+
+		refs, err := bp.refs(pos, count)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result := make([]any, 0, count)
+
+		for _, ref := range refs {
+			value, err := bp.object(ref, depth+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result = append(result, value)
+		}
+
+		return result, nil
+
+	case 0xd0:
+		count, pos, err := bp.length(marker, pos)
+
+		if err != nil {
+			return nil, err
+		}
+
+		//
+		// The keys are followed by as many values (so count cannot overflow
+		// when doubled, as it cannot exceed the length of the data):
+		//
+		if count > uint64(len(bp.data)) {
+			return nil, errors.New("Truncated binary plist")
+		}
+
+		refs, err := bp.refs(pos, count*2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		result := make(map[string]any, count)
+
+		for idx := uint64(0); idx < count; idx++ {
+			key, err := bp.object(refs[idx], depth+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := bp.object(refs[idx+count], depth+1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			result[fmt.Sprint(key)] = value
+		}
+
+		return result, nil
+
+	default:
+		return nil, fmt.Errorf("Unsupported binary plist marker: 0x%02x", marker)
+	}
+}
+
+// check verifies that size bytes are available at pos (without computing
+// pos+size, which could overflow).
+func (bp *binaryPlist) check(pos, size uint64) error {
+	if pos > uint64(len(bp.data)) || size > uint64(len(bp.data))-pos {
+		return errors.New("Truncated binary plist")
+	}
+
+	return nil
+}
+
+// checkArray verifies that count elements of elementSize bytes each are
+// available at pos (without computing count*elementSize, which could
+// overflow).
+func (bp *binaryPlist) checkArray(pos, count, elementSize uint64) error {
+	if pos > uint64(len(bp.data)) || count > (uint64(len(bp.data))-pos)/elementSize {
+		return errors.New("Truncated binary plist")
+	}
+
+	return nil
+}
+
+func (bp *binaryPlist) refs(pos, count uint64) ([]uint64, error) {
+	size := uint64(bp.objectRefSz)
+
+	if err := bp.checkArray(pos, count, size); err != nil {
+		return nil, err
+	}
+
+	refs := make([]uint64, count)
+
+	for idx := range refs {
+		start := pos + uint64(idx)*size
+
+		refs[idx] = readUint(bp.data[start : start+size])
+	}
+
+	return refs, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func decodeXMLPlist(data []byte) (any, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	decoder.Strict = false
+
+	for {
+		token, err := decoder.Token()
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid XML plist, error: %v", err)
+		}
+
+		if se, ok := token.(xml.StartElement); ok && se.Name.Local != "plist" {
+			return decodeXMLValue(decoder, se)
+		}
+	}
+}
+
+func decodeXMLValue(decoder *xml.Decoder, se xml.StartElement) (any, error) {
+	switch se.Name.Local {
+	case "array":
+		var result []any
+
+		for {
+			token, err := decoder.Token()
+
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				value, err := decodeXMLValue(decoder, t)
+
+				if err != nil {
+					return nil, err
+				}
+
+				result = append(result, value)
+
+			case xml.EndElement:
+				return result, nil
+			}
+		}
+
+	case "dict":
+		result := make(map[string]any)
+
+		var key string
+
+		for {
+			token, err := decoder.Token()
+
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := token.(type) {
+			case xml.StartElement:
+				if t.Name.Local == "key" {
+					if key, err = readXMLText(decoder); err != nil {
+						return nil, err
+					}
+
+					continue
+				}
+
+				value, err := decodeXMLValue(decoder, t)
+
+				if err != nil {
+					return nil, err
+				}
+
+				result[key] = value
+
+			case xml.EndElement:
+				return result, nil
+			}
+		}
+
+	case "false", "true":
+		if err := decoder.Skip(); err != nil {
+			return nil, err
+		}
+
+		return se.Name.Local == "true", nil
+
+	case "data":
+		text, err := readXMLText(decoder)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(text), ""))
+
+	case "integer":
+		text, err := readXMLText(decoder)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseInt(strings.TrimSpace(text), 0, 64)
+
+	case "real":
+		text, err := readXMLText(decoder)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+
+	default: // incl. "date" and "string"
+		return readXMLText(decoder)
+	}
+}
+
+func readXMLText(decoder *xml.Decoder) (string, error) {
+	var sb strings.Builder
+
+	for {
+		token, err := decoder.Token()
+
+		if errors.Is(err, io.EOF) {
+			return "", io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.CharData:
+			sb.Write(t)
+
+		case xml.EndElement:
+			return sb.String(), nil
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func readUint(data []byte) uint64 {
+	var value uint64
+
+	for _, b := range data {
+		value = value<<8 | uint64(b)
+	}
+
+	return value
+}
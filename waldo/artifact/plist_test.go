@@ -0,0 +1,165 @@
+package artifact
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func readTestData(t *testing.T, name string) []byte {
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+// makeBinaryPlist assembles a binary plist from the given encoded objects
+// (the first being the top object), with an offset table and trailer that can
+// be tampered with afterwards.
+func makeBinaryPlist(objects ...[]byte) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString("bplist00")
+
+	var offsets []uint64
+
+	for _, object := range objects {
+		offsets = append(offsets, uint64(buf.Len()))
+
+		buf.Write(object)
+	}
+
+	offsetTableOffset := uint64(buf.Len())
+
+	for _, offset := range offsets {
+		binary.Write(&buf, binary.BigEndian, uint16(offset))
+	}
+
+	trailer := make([]byte, 32)
+
+	trailer[6] = 2 // offset int size
+	trailer[7] = 1 // object ref size
+
+	binary.BigEndian.PutUint64(trailer[8:], uint64(len(objects)))
+	binary.BigEndian.PutUint64(trailer[24:], offsetTableOffset)
+
+	buf.Write(trailer)
+
+	return buf.Bytes()
+}
+
+func setTrailerUint64(data []byte, offset int, value uint64) []byte {
+	result := append([]byte{}, data...)
+
+	binary.BigEndian.PutUint64(result[len(result)-32+offset:], value)
+
+	return result
+}
+
+func TestDecodePlistFixtures(t *testing.T) {
+	want := map[string]any{
+		"Blob":                         []byte{0, 1, 2},
+		"CFBundleDisplayName":          "Sämple ✓",
+		"CFBundleExecutable":           "Sample",
+		"CFBundleIdentifier":           "com.example.sample",
+		"CFBundleName":                 "Sample",
+		"CFBundleShortVersionString":   "1.2.3",
+		"CFBundleVersion":              "42",
+		"LSRequiresIPhoneOS":           true,
+		"MinimumOSVersion":             "15.0",
+		"Ratio":                        1.5,
+		"UIDeviceFamily":               []any{int64(1), int64(2)},
+		"UIRequiredDeviceCapabilities": []any{"arm64"}}
+
+	for _, name := range []string{"Info.binary.plist", "Info.xml.plist"} {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodePlist(readTestData(t, name))
+
+			if err != nil {
+				t.Fatalf("decodePlist() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("decodePlist() = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeBinaryPlistTruncated(t *testing.T) {
+	data := readTestData(t, "Info.binary.plist")
+
+	//
+	// Every truncation must fail cleanly (never panic):
+	//
+	for size := 0; size < len(data); size++ {
+		if _, err := decodePlist(data[:size]); err == nil && size < 8+32 {
+			t.Errorf("decodePlist() of %d bytes succeeded", size)
+		}
+	}
+}
+
+func TestDecodeBinaryPlistMalicious(t *testing.T) {
+	valid := makeBinaryPlist([]byte{0x51, 'x'})
+
+	if got, err := decodePlist(valid); err != nil || got != "x" {
+		t.Fatalf("decodePlist() of valid plist = %v, %v", got, err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"offset table offset overflow", setTrailerUint64(valid, 24, math.MaxUint64)},
+		{"object count overflow", setTrailerUint64(valid, 8, math.MaxUint64/2+1)},
+		{"object count too large", setTrailerUint64(valid, 8, 1000)},
+		{"top object out of range", setTrailerUint64(valid, 16, 5)},
+		{"invalid offset int size", func() []byte {
+			data := append([]byte{}, valid...)
+
+			data[len(data)-32+6] = 0
+
+			return data
+		}()},
+		{"string length overflow", makeBinaryPlist([]byte{0x5f, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})},
+		{"UTF-16 string count overflow", makeBinaryPlist([]byte{0x6f, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 1})},
+		{"array count overflow", makeBinaryPlist([]byte{0xaf, 0x13, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})},
+		{"dict count overflow", makeBinaryPlist([]byte{0xdf, 0x13, 0x80, 0, 0, 0, 0, 0, 0, 0})},
+		{"object offset out of range", func() []byte {
+			data := append([]byte{}, valid...)
+
+			binary.BigEndian.PutUint16(data[len(data)-32-2:], 0xffff)
+
+			return data
+		}()},
+		{"self-referencing array", makeBinaryPlist([]byte{0xa1, 0x00})},
+		{"unsupported marker", makeBinaryPlist([]byte{0xf0})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := decodePlist(tt.data); err == nil {
+				t.Errorf("decodePlist() = %v, want error", got)
+			}
+		})
+	}
+}
+
+func TestDecodeXMLPlistInvalid(t *testing.T) {
+	for _, data := range []string{
+		"",
+		"<plist><dict><key>a</key>",
+		"<plist><integer>abc</integer></plist>",
+		"<plist><data>!!</data></plist>"} {
+		if got, err := decodePlist([]byte(data)); err == nil {
+			t.Errorf("decodePlist(%q) = %v, want error", data, got)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package artifact
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+const (
+	unpackMaxEntries = 200_000
+	unpackMaxSize    = 8 * 1024 * 1024 * 1024 // 8 GiB
+)
+
+//-----------------------------------------------------------------------------
+
+type Unpacker struct {
+	buildPath      string
+	bundletoolPath string
+	ioStreams      *lib.IOStreams
+	verbose        bool
+	workingPath    string
+}
+
+//-----------------------------------------------------------------------------
+
+func NewUnpacker(buildPath, bundletoolPath string, verbose bool, ioStreams *lib.IOStreams) *Unpacker {
+	return &Unpacker{
+		buildPath:      buildPath,
+		bundletoolPath: bundletoolPath,
+		ioStreams:      ioStreams,
+		verbose:        verbose}
+}
+
+//-----------------------------------------------------------------------------
+
+// MakeExtractLimits returns the limits within which an archived build artifact
+// is extracted.
+func MakeExtractLimits() *lib.ExtractLimits {
+	return &lib.ExtractLimits{
+		MaxEntries: unpackMaxEntries,
+		MaxSize:    unpackMaxSize}
+}
+
+//-----------------------------------------------------------------------------
+
+func (u *Unpacker) Cleanup() {
+	if len(u.workingPath) > 0 {
+		os.RemoveAll(u.workingPath)
+	}
+}
+
+func (u *Unpacker) Unpack() (string, error) {
+	var (
+		appPath string
+		err     error
+	)
+
+	switch kind := DetectKind(u.buildPath); kind {
+	case KindAab, KindApk, KindApp:
+		appPath = u.buildPath
+
+	case KindTarGz, KindZip:
+		appPath, err = u.extract(kind)
+
+	case KindXcarchive:
+		appPath, err = findSingleCandidate(u.buildPath, u.buildPath)
+
+	default:
+		if lib.IsDirectory(u.buildPath) { // e.g., a tarball extracted as downloaded
+			appPath, err = findSingleCandidate(u.buildPath, u.buildPath)
+
+			break
+		}
+
+		err = fmt.Errorf("Unsupported build artifact: %q", u.buildPath)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if DetectKind(appPath) == KindAab {
+		appPath, err = u.convertBundle(appPath)
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := Validate(appPath); err != nil {
+		return "", err
+	}
+
+	return appPath, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (u *Unpacker) extract(kind Kind) (string, error) {
+	workingPath, err := os.MkdirTemp("", "WaldoGoCLI-unpack-")
+
+	if err != nil {
+		return "", err
+	}
+
+	u.workingPath = workingPath
+
+	u.ioStreams.Printf("\nExtracting build from %q\n", trimBase(u.buildPath))
+
+	limits := MakeExtractLimits()
+
+	if kind == KindTarGz {
+		err = lib.ExtractTarGz(u.buildPath, workingPath, limits)
+	} else {
+		err = lib.ExtractZip(u.buildPath, workingPath, limits)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to extract build, error: %v, path: %q", err, u.buildPath)
+	}
+
+	return findSingleCandidate(workingPath, u.buildPath)
+}
+
+//-----------------------------------------------------------------------------
+
+func findCandidates(rootPath string) ([]string, error) {
+	var candidates []string
+
+	err := filepath.WalkDir(rootPath, func(path string, de fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name := de.Name()
+
+		if de.IsDir() {
+			if name == "__MACOSX" {
+				return filepath.SkipDir
+			}
+
+			switch DetectKind(path) {
+			case KindApp:
+				candidates = append(candidates, path)
+
+				return filepath.SkipDir // ignore nested (e.g., watch) apps
+
+			case KindXcarchive:
+				appPaths := lib.FindDirectoryPathsMatching(filepath.Join(path, "Products", "Applications", "*.app"))
+
+				candidates = append(candidates, appPaths...)
+
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !de.Type().IsRegular() {
+			return nil
+		}
+
+		switch strings.ToLower(filepath.Ext(name)) {
+		case ".aab", ".apk":
+			candidates = append(candidates, path)
+		}
+
+		return nil
+	})
+
+	return candidates, err
+}
+
+func findSingleCandidate(rootPath, buildPath string) (string, error) {
+	candidates, err := findCandidates(rootPath)
+
+	if err != nil {
+		return "", fmt.Errorf("Unable to search build, error: %v, path: %q", err, buildPath)
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("No .aab, .apk, or .app found in build artifact: %q", buildPath)
+
+	case 1:
+		return candidates[0], nil
+
+	default:
+		names := lib.Map(candidates, func(candidate string) string {
+			return lib.MakeRelative(candidate, rootPath)
+		})
+
+		return "", fmt.Errorf("Multiple builds found in build artifact: %q (%v)", buildPath, strings.Join(names, ", "))
+	}
+}
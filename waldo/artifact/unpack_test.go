@@ -0,0 +1,155 @@
+package artifact
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// writeTestZip writes a zip of the given entries; an entry whose contents
+// start with “->” is a symlink to the remainder.
+func writeTestZip(t *testing.T, entries [][2]string) string {
+	zipPath := filepath.Join(t.TempDir(), "Sample.zip")
+
+	file, err := os.Create(zipPath)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zw := zip.NewWriter(file)
+
+	for _, entry := range entries {
+		hdr := &zip.FileHeader{Name: entry[0], Method: zip.Store}
+		contents := entry[1]
+
+		if target, ok := strings.CutPrefix(contents, "->"); ok {
+			hdr.SetMode(fs.ModeSymlink | 0777)
+
+			contents = target
+		} else {
+			hdr.SetMode(0644)
+		}
+
+		w, err := zw.CreateHeader(hdr)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(contents))
+	}
+
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file.Close()
+
+	return zipPath
+}
+
+func TestUnpackerUnpack(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  [][2]string
+		wantPath string
+		wantErr  string
+	}{
+		{
+			name: "app",
+			entries: [][2]string{
+				{"Payload/Sample.app/Info.plist", "<plist/>"},
+				{"Payload/Sample.app/PlugIns/Watch.app/Info.plist", "<plist/>"},
+				{"__MACOSX/Other.app/Info.plist", "<plist/>"}},
+			wantPath: filepath.Join("Payload", "Sample.app")},
+		{
+			name: "multiple candidates",
+			entries: [][2]string{
+				{"One.app/Info.plist", "<plist/>"},
+				{"Two.app/Info.plist", "<plist/>"}},
+			wantErr: "Multiple builds found"},
+		{
+			name:    "no candidate",
+			entries: [][2]string{{"README.txt", "nothing here"}},
+			wantErr: "No .aab, .apk, or .app found"},
+		{
+			name: "app without Info.plist",
+			entries: [][2]string{
+				{"Sample.app/Sample", "binary"}},
+			wantErr: "no Info.plist found"},
+		{
+			name:    "entry escapes",
+			entries: [][2]string{{"../Sample.app/Info.plist", "<plist/>"}},
+			wantErr: "Unable to extract build"},
+		{
+			name: "symlink escapes",
+			entries: [][2]string{
+				{"Sample.app/Info.plist", "<plist/>"},
+				{"Sample.app/Outside", "->../../outside"}},
+			wantErr: "Unable to extract build"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TMPDIR", t.TempDir())
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+			unpacker := NewUnpacker(writeTestZip(t, tt.entries), "", false, ioStreams)
+
+			defer unpacker.Cleanup()
+
+			appPath, err := unpacker.Unpack()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Unpack() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+
+			if !strings.HasSuffix(appPath, string(filepath.Separator)+tt.wantPath) {
+				t.Errorf("Unpack() = %q, want …/%v", appPath, tt.wantPath)
+			}
+
+			unpacker.Cleanup()
+
+			if lib.IsDirectory(appPath) {
+				t.Errorf("Cleanup() left %q", appPath)
+			}
+		})
+	}
+}
+
+func TestUnpackerUnpackDirectory(t *testing.T) {
+	dirPath := filepath.Join(t.TempDir(), "extracted")
+	appPath := filepath.Join(dirPath, "Sample.app")
+
+	if err := os.MkdirAll(appPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(appPath, "Info.plist"), []byte("<plist/>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+	unpacker := NewUnpacker(dirPath, "", false, ioStreams)
+
+	defer unpacker.Cleanup()
+
+	if path, err := unpacker.Unpack(); err != nil || path != appPath {
+		t.Errorf("Unpack() = %q, %v, want %q", path, err, appPath)
+	}
+}
@@ -0,0 +1,37 @@
+package artifact
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+func Validate(path string) error {
+	switch DetectKind(path) {
+	case KindApk:
+		if !lib.IsRegularFile(path) {
+			return fmt.Errorf("Invalid Android build, not a file: %q", path)
+		}
+
+		if !hasZipEntry(path, "AndroidManifest.xml") {
+			return fmt.Errorf("Invalid Android build, no AndroidManifest.xml found: %q", path)
+		}
+
+		return nil
+
+	case KindApp:
+		if !lib.IsDirectory(path) {
+			return fmt.Errorf("Invalid iOS build, not a directory: %q", path)
+		}
+
+		if !lib.IsRegularFile(filepath.Join(path, "Info.plist")) {
+			return fmt.Errorf("Invalid iOS build, no Info.plist found: %q", path)
+		}
+
+		return nil
+
+	default:
+		return fmt.Errorf("Unsupported build artifact: %q", path)
+	}
+}
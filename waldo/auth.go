@@ -1,7 +1,9 @@
 package waldo
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/waldoapp/waldo-go-cli/lib"
 	"github.com/waldoapp/waldo-go-cli/waldo/api"
@@ -9,8 +11,12 @@ import (
 )
 
 type AuthOptions struct {
-	APIToken string
-	Verbose  bool
+	APIToken   string
+	Encrypt    bool
+	Profile    string
+	TokenFile  string
+	TokenStdin bool
+	Verbose    bool
 }
 
 type AuthAction struct {
@@ -33,13 +39,19 @@ func NewAuthAction(options *AuthOptions, ioStreams *lib.IOStreams) *AuthAction {
 //-----------------------------------------------------------------------------
 
 func (aa *AuthAction) Perform() error {
-	if err := data.ValidateAPIToken(aa.options.APIToken); err != nil {
+	apiToken, err := aa.detectAPIToken()
+
+	if err != nil {
+		return err
+	}
+
+	if err := data.ValidateAPIToken(apiToken); err != nil {
 		return err
 	}
 
-	aa.ioStreams.Printf("\nAuthenticating with API token %q\n", aa.options.APIToken)
+	aa.ioStreams.Printf("\nAuthenticating with API token %q\n", maskToken(apiToken))
 
-	fullName, err := api.AuthenticateUser(aa.options.APIToken, aa.options.Verbose, aa.ioStreams)
+	aur, err := api.AuthenticateUser(apiToken, aa.options.Verbose, aa.ioStreams)
 
 	if err != nil {
 		return err
@@ -51,15 +63,109 @@ func (aa *AuthAction) Perform() error {
 		return fmt.Errorf("Unable to authenticate user, error: %v", err)
 	}
 
-	profile.APIToken = aa.options.APIToken
+	name := profile.ResolveName(aa.options.Profile)
+
+	if err := data.ValidateProfileName(name); err != nil {
+		return err
+	}
+
+	store, err := openCredentialStore(profile, aa.ioStreams)
 
-	profile.MarkDirty()
+	if err != nil {
+		return err
+	}
 
-	if err := profile.Save(); err != nil {
+	if aa.options.Encrypt && !store.IsEncrypted() {
+		fcs, ok := store.(*fileCredentialStore)
+
+		if !ok {
+			return fmt.Errorf("Option %q not allowed with credential helper", "--encrypt")
+		}
+
+		passphrase, err := chooseCredentialsPassphrase(aa.ioStreams)
+
+		if err != nil {
+			return err
+		}
+
+		fcs.setPassphrase(passphrase)
+	}
+
+	if err := store.Store(name, apiToken); err != nil {
 		return fmt.Errorf("Unable to authenticate user, error: %v", err)
 	}
 
-	aa.ioStreams.Printf("\nUser %q successfully authenticated -- credentials saved to %q\n", fullName, profile.Path())
+	if _, err := data.UpdateProfile(data.CreateKindIfNeeded, func(prf *data.Profile) error {
+		if prf.Find(name) == nil {
+			prf.Set(name, &data.ProfileEntry{})
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("Unable to authenticate user, error: %v", err)
+	}
+
+	encrypted := ""
+
+	if store.IsEncrypted() {
+		encrypted = " (encrypted)"
+	}
+
+	aa.ioStreams.Printf("\nUser %q successfully authenticated -- credentials for profile %q saved to %v%v\n", aur.FullName(), name, store.Describe(), encrypted)
 
 	return nil
 }
+
+//-----------------------------------------------------------------------------
+
+func (aa *AuthAction) detectAPIToken() (string, error) {
+	sources := 0
+
+	for _, given := range []bool{len(aa.options.APIToken) > 0, len(aa.options.TokenFile) > 0, aa.options.TokenStdin} {
+		if given {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return "", fmt.Errorf("Only one of %q, %q, or %q allowed", "<api-token>", "--token_file", "--token_stdin")
+	}
+
+	switch {
+	case len(aa.options.APIToken) > 0:
+		aa.ioStreams.EmitError(data.CLIPrefix, errors.New("An API token on the command line may be exposed in shell history and process listings -- prefer the prompt, --token_stdin, or --token_file"))
+
+		return aa.options.APIToken, nil
+
+	case len(aa.options.TokenFile) > 0:
+		apiToken, err := lib.ReadSecretFile(aa.options.TokenFile)
+
+		if err != nil {
+			return "", fmt.Errorf("Unable to read API token, error: %v", err)
+		}
+
+		return apiToken, nil
+
+	case aa.options.TokenStdin:
+		input, err := aa.ioStreams.ReadAll()
+
+		if err != nil {
+			return "", fmt.Errorf("Unable to read API token, error: %v", err)
+		}
+
+		return strings.TrimSpace(string(input)), nil
+
+	default:
+		apiToken, err := aa.ioStreams.PromptReader().ReadSecret("API token")
+
+		if errors.Is(err, lib.ErrInterrupted) {
+			return "", err
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("No API token specified -- use %q or %q when not running interactively", "--token_stdin", "--token_file")
+		}
+
+		return strings.TrimSpace(apiToken), nil
+	}
+}
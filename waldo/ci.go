@@ -0,0 +1,27 @@
+package waldo
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/ci"
+)
+
+func detectCIInfo() *ci.Info {
+	return ci.Detect(lib.CurrentEnvironment())
+}
+
+func emitCIInfo(ioStreams *lib.IOStreams, ciInfo *ci.Info) {
+	if ciInfo == nil {
+		return
+	}
+
+	ioStreams.Printf("  ci provider:      %v\n", ciInfo.Provider)
+	ioStreams.Printf("  ci build:         %v %v\n", ciInfo.BuildNumber, ciInfo.BuildURL)
+
+	if len(ciInfo.PullRequest) > 0 {
+		ioStreams.Printf("  ci pull request:  %v (%v -> %v)\n", ciInfo.PullRequest, ciInfo.Branch, ciInfo.BaseBranch)
+	}
+
+	if len(ciInfo.Actor) > 0 {
+		ioStreams.Printf("  ci actor:         %v\n", ciInfo.Actor)
+	}
+}
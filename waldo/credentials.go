@@ -0,0 +1,166 @@
+package waldo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+// credentialStore saves API tokens by profile name, either in the credentials
+// file or through an external credential helper.
+type credentialStore interface {
+	Describe() string
+	Erase(name string) error
+	Get(name string) (string, error)
+	IsEncrypted() bool
+	Store(name, token string) error
+}
+
+type fileCredentialStore struct {
+	crd        *data.Credentials
+	passphrase *string // to encrypt with on the next update, if set
+}
+
+type helperCredentialStore struct {
+	helper *data.CredentialHelper
+}
+
+//-----------------------------------------------------------------------------
+
+func (fcs *fileCredentialStore) Describe() string {
+	return fmt.Sprintf("%q", fcs.crd.Path())
+}
+
+func (fcs *fileCredentialStore) Erase(name string) error {
+	return fcs.update(func(crd *data.Credentials) {
+		crd.RemoveToken(name)
+	})
+}
+
+func (fcs *fileCredentialStore) Get(name string) (string, error) {
+	return fcs.crd.Token(name), nil
+}
+
+func (fcs *fileCredentialStore) IsEncrypted() bool {
+	if fcs.passphrase != nil {
+		return len(*fcs.passphrase) > 0
+	}
+
+	return fcs.crd.IsEncrypted()
+}
+
+func (fcs *fileCredentialStore) Store(name, token string) error {
+	return fcs.update(func(crd *data.Credentials) {
+		crd.SetToken(name, token)
+	})
+}
+
+// setPassphrase makes the next update encrypt the credentials with the given
+// passphrase (or store them unencrypted if it is empty).
+func (fcs *fileCredentialStore) setPassphrase(passphrase string) {
+	fcs.passphrase = &passphrase
+}
+
+func (fcs *fileCredentialStore) update(updateFunc func(crd *data.Credentials)) error {
+	return fcs.crd.Update(func(crd *data.Credentials) {
+		if fcs.passphrase != nil {
+			crd.SetPassphrase(*fcs.passphrase)
+
+			fcs.passphrase = nil
+		}
+
+		updateFunc(crd)
+	})
+}
+
+//-----------------------------------------------------------------------------
+
+func (hcs *helperCredentialStore) Describe() string {
+	return fmt.Sprintf("credential helper %q", hcs.helper.Command())
+}
+
+func (hcs *helperCredentialStore) Erase(name string) error {
+	return hcs.helper.Erase(name)
+}
+
+func (hcs *helperCredentialStore) Get(name string) (string, error) {
+	return hcs.helper.Get(name)
+}
+
+func (hcs *helperCredentialStore) IsEncrypted() bool {
+	return false // up to the helper
+}
+
+func (hcs *helperCredentialStore) Store(name, token string) error {
+	return hcs.helper.Store(name, token)
+}
+
+//-----------------------------------------------------------------------------
+
+func chooseCredentialsPassphrase(ioStreams *lib.IOStreams) (string, error) {
+	passphrase, err := lib.GetenvSecret("WALDO_CREDENTIALS_PASSPHRASE")
+
+	if err != nil || len(passphrase) > 0 {
+		return passphrase, err
+	}
+
+	pr := ioStreams.PromptReader()
+
+	passphrase, err = pr.ReadSecret("New passphrase for Waldo credentials")
+
+	if errors.Is(err, lib.ErrInterrupted) {
+		return "", err
+	}
+
+	if err != nil {
+		return "", errors.New("Unable to prompt for passphrase -- set WALDO_CREDENTIALS_PASSPHRASE instead")
+	}
+
+	if len(passphrase) == 0 {
+		return "", errors.New("No passphrase specified")
+	}
+
+	confirmation, err := pr.ReadSecret("Confirm passphrase")
+
+	if errors.Is(err, lib.ErrInterrupted) {
+		return "", err
+	}
+
+	if err != nil || confirmation != passphrase {
+		return "", errors.New("Passphrases do not match")
+	}
+
+	return passphrase, nil
+}
+
+// openCredentialStore returns the credential helper configured by the
+// WALDO_CREDENTIAL_HELPER environment variable or the credential_helper
+// setting in the Waldo profile, if any, else the credentials file.
+func openCredentialStore(profile *data.Profile, ioStreams *lib.IOStreams) (credentialStore, error) {
+	command := os.Getenv("WALDO_CREDENTIAL_HELPER")
+
+	if len(command) == 0 && profile != nil {
+		command = profile.CredentialHelper
+	}
+
+	if len(command) > 0 {
+		return &helperCredentialStore{helper: data.NewCredentialHelper(command)}, nil
+	}
+
+	crd, err := setupCredentials(ioStreams)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &fileCredentialStore{crd: crd}, nil
+}
+
+func setupCredentials(ioStreams *lib.IOStreams) (*data.Credentials, error) {
+	return data.SetupCredentials(func() (string, error) {
+		return ioStreams.PromptReader().ReadSecret("Passphrase to unlock Waldo credentials")
+	})
+}
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/tpw"
+)
+
+const (
+	cfgFormatVersion = 1
+)
+
+//-----------------------------------------------------------------------------
+
+type Configuration struct {
+	FormatVersion  int                      `yaml:"format_version"`
+	BundletoolPath string                   `yaml:"bundletool_path,omitempty"`
+	Metadata       map[string]string        `yaml:"metadata,omitempty"`
+	Policy         PolicyConfig             `yaml:"policy,omitempty"`
+	ReleaseNotes   ReleaseNotesConfig       `yaml:"release_notes,omitempty"`
+	Targets        map[string]*TargetConfig `yaml:"targets,omitempty"`
+	TriggerRules   []*TriggerRuleConfig     `yaml:"trigger_rules,omitempty"`
+
+	basePath   string // absolute
+	configPath string // absolute
+}
+
+type PolicyConfig struct {
+	AllowedBranches          []string `yaml:"allowed_branches,omitempty"` // globs, as in path.Match
+	MinBuildVersionIncrement int      `yaml:"min_build_version_increment,omitempty"`
+	RejectDirty              bool     `yaml:"reject_dirty,omitempty"`
+	RequireGitCommit         bool     `yaml:"require_git_commit,omitempty"`
+	RequireVariantName       bool     `yaml:"require_variant_name,omitempty"`
+}
+
+type ReleaseNotesConfig struct {
+	Limit    int    `yaml:"limit,omitempty"`
+	Template string `yaml:"template,omitempty"`
+}
+
+type TargetConfig struct {
+	AppID            string `yaml:"app_id,omitempty"`
+	BuildPath        string `yaml:"build_path,omitempty"` // glob, relative to base path
+	PathScopedCommit bool   `yaml:"path_scoped_commit,omitempty"`
+	Platform         string `yaml:"platform,omitempty"`
+	SourcePath       string `yaml:"source_path,omitempty"` // relative to base path
+	VariantName      string `yaml:"variant_name,omitempty"`
+}
+
+type TriggerRuleConfig struct {
+	Branch      string `yaml:"branch"` // glob, as in path.Match
+	RuleName    string `yaml:"rule_name,omitempty"`
+	Skip        bool   `yaml:"skip,omitempty"`
+	VariantName string `yaml:"variant_name,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func LoadConfiguration() (*Configuration, error) {
+	cfg := &Configuration{
+		FormatVersion: cfgFormatVersion}
+
+	configPath := findConfigurationPath()
+
+	if len(configPath) == 0 {
+		return cfg, nil
+	}
+
+	cfg.basePath = filepath.Dir(filepath.Dir(configPath))
+	cfg.configPath = configPath
+
+	if err := cfg.load(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (cfg *Configuration) BasePath() string {
+	return cfg.basePath
+}
+
+func (cfg *Configuration) Path() string {
+	return cfg.configPath
+}
+
+//-----------------------------------------------------------------------------
+
+func findConfigurationPath() string {
+	dirPath, err := os.Getwd()
+
+	if err != nil {
+		return ""
+	}
+
+	for {
+		cfgPath := filepath.Join(dirPath, ".waldo", "config.yml")
+
+		if lib.IsRegularFile(cfgPath) {
+			return cfgPath
+		}
+
+		parentPath := filepath.Dir(dirPath)
+
+		if parentPath == dirPath {
+			return ""
+		}
+
+		dirPath = parentPath
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func (cfg *Configuration) load() error {
+	data, err := os.ReadFile(cfg.configPath)
+
+	if err != nil {
+		return err
+	}
+
+	return tpw.DecodeFromYAML(data, cfg)
+}
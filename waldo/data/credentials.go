@@ -0,0 +1,352 @@
+package data
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/tpw"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	crdFormatVersion = 1
+
+	crdCipher        = "aes-256-gcm"
+	crdIterations    = 600_000
+	crdMaxIterations = 10_000_000 // bounds the work a tampered file can demand
+	crdKDF           = "pbkdf2-sha256"
+	crdKeyLength     = 32
+	crdLockTimeout   = 30 * time.Second
+	crdSaltLength    = 16
+)
+
+var crdAdditionalData = []byte("waldo-credentials")
+
+//-----------------------------------------------------------------------------
+
+// Credentials is the store of API tokens (keyed by profile name), kept apart
+// from the profile in a file readable only by its owner. The tokens are
+// optionally encrypted with a key derived from a passphrase.
+type Credentials struct {
+	FormatVersion int                    `yaml:"format_version"`
+	Encryption    *CredentialsEncryption `yaml:"encryption,omitempty"`
+	Tokens        map[string]string      `yaml:"tokens,omitempty"` // unencrypted only
+
+	credentialsPath string // absolute
+	dirty           bool
+	passphrase      string
+	tokens          map[string]string
+}
+
+type CredentialsEncryption struct {
+	Cipher     string `yaml:"cipher"`
+	Ciphertext string `yaml:"ciphertext"` // base64
+	Iterations int    `yaml:"iterations"`
+	KDF        string `yaml:"kdf"`
+	Nonce      string `yaml:"nonce"` // base64
+	Salt       string `yaml:"salt"`  // base64
+}
+
+// PassphraseFunc supplies the passphrase for encrypted credentials when it is
+// not available from the WALDO_CREDENTIALS_PASSPHRASE environment variable.
+type PassphraseFunc func() (string, error)
+
+//-----------------------------------------------------------------------------
+
+func SetupCredentials(passphraseFunc PassphraseFunc) (*Credentials, error) {
+	dataPath, err := makeHomeDataPath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	crd := &Credentials{
+		credentialsPath: filepath.Join(dataPath, "credentials.yml")}
+
+	if err := crd.reload(passphraseFunc); err != nil {
+		return nil, err
+	}
+
+	return crd, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (crd *Credentials) IsEncrypted() bool {
+	return len(crd.passphrase) > 0
+}
+
+func (crd *Credentials) MarkDirty() {
+	crd.dirty = true
+}
+
+func (crd *Credentials) Path() string {
+	return crd.credentialsPath
+}
+
+func (crd *Credentials) RemoveToken(name string) bool {
+	if _, found := crd.tokens[name]; !found {
+		return false
+	}
+
+	delete(crd.tokens, name)
+
+	crd.MarkDirty()
+
+	return true
+}
+
+func (crd *Credentials) Save() error {
+	if !crd.dirty {
+		return nil
+	}
+
+	if crd.IsEncrypted() {
+		if err := crd.encrypt(); err != nil {
+			return err
+		}
+
+		crd.Tokens = nil
+	} else {
+		crd.Encryption = nil
+		crd.Tokens = crd.tokens
+	}
+
+	data, err := tpw.EncodeToYAML(crd)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(crd.credentialsPath), 0755); err != nil {
+		return err
+	}
+
+	if err := lib.WriteFileAtomic(crd.credentialsPath, data, 0600); err != nil {
+		return err
+	}
+
+	crd.dirty = false
+
+	return nil
+}
+
+// SetPassphrase encrypts the credentials with the given passphrase when next
+// saved, or stores them unencrypted if the passphrase is empty.
+func (crd *Credentials) SetPassphrase(passphrase string) {
+	crd.passphrase = passphrase
+
+	crd.MarkDirty()
+}
+
+func (crd *Credentials) SetToken(name, token string) {
+	crd.tokens[name] = token
+
+	crd.MarkDirty()
+}
+
+func (crd *Credentials) Token(name string) string {
+	return crd.tokens[name]
+}
+
+// Update applies updateFunc to the credentials and saves them. It holds a lock
+// on the credentials file throughout, and reloads them once the lock is held,
+// so that concurrent updates (for example, “auth --profile a” and “auth
+// --profile b”) do not lose each other's tokens.
+func (crd *Credentials) Update(updateFunc func(crd *Credentials)) error {
+	if err := os.MkdirAll(filepath.Dir(crd.credentialsPath), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lib.LockFile(crd.credentialsPath, crdLockTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	passphrase := crd.passphrase
+
+	if err := crd.reload(func() (string, error) { return passphrase, nil }); err != nil {
+		return err
+	}
+
+	updateFunc(crd)
+
+	return crd.Save()
+}
+
+//-----------------------------------------------------------------------------
+
+func (crd *Credentials) decrypt(passphrase string) error {
+	enc := crd.Encryption
+
+	if enc.KDF != crdKDF || enc.Cipher != crdCipher {
+		return fmt.Errorf("Unsupported Waldo credentials encryption: %v/%v", enc.KDF, enc.Cipher)
+	}
+
+	salt, err := base64.StdEncoding.DecodeString(enc.Salt)
+
+	if err != nil {
+		return err
+	}
+
+	nonce, err := base64.StdEncoding.DecodeString(enc.Nonce)
+
+	if err != nil {
+		return err
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(enc.Ciphertext)
+
+	if err != nil {
+		return err
+	}
+
+	aead, err := makeAEAD(passphrase, salt, enc.Iterations)
+
+	if err != nil {
+		return err
+	}
+
+	if len(nonce) != aead.NonceSize() {
+		return fmt.Errorf("Invalid Waldo credentials nonce length: %d", len(nonce))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, crdAdditionalData)
+
+	if err != nil {
+		return errors.New("Unable to unlock Waldo credentials -- wrong passphrase?")
+	}
+
+	if err := json.Unmarshal(plaintext, &crd.tokens); err != nil {
+		return err
+	}
+
+	crd.passphrase = passphrase
+
+	return nil
+}
+
+func (crd *Credentials) encrypt() error {
+	plaintext, err := json.Marshal(crd.tokens)
+
+	if err != nil {
+		return err
+	}
+
+	//
+	// A fresh salt and nonce on every save; never reuse a nonce with a key:
+	//
+	salt := make([]byte, crdSaltLength)
+
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := makeAEAD(crd.passphrase, salt, crdIterations)
+
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	crd.Encryption = &CredentialsEncryption{
+		Cipher:     crdCipher,
+		Ciphertext: base64.StdEncoding.EncodeToString(aead.Seal(nil, nonce, plaintext, crdAdditionalData)),
+		Iterations: crdIterations,
+		KDF:        crdKDF,
+		Nonce:      base64.StdEncoding.EncodeToString(nonce),
+		Salt:       base64.StdEncoding.EncodeToString(salt)}
+
+	return nil
+}
+
+func (crd *Credentials) load() error {
+	data, err := os.ReadFile(crd.credentialsPath)
+
+	if err != nil {
+		return err
+	}
+
+	return tpw.DecodeFromYAML(data, crd)
+}
+
+// reload discards any unsaved changes and loads the credentials afresh,
+// unlocking them with the passphrase from WALDO_CREDENTIALS_PASSPHRASE or else
+// from passphraseFunc (if any) when they are encrypted.
+func (crd *Credentials) reload(passphraseFunc PassphraseFunc) error {
+	*crd = Credentials{
+		FormatVersion:   crdFormatVersion,
+		credentialsPath: crd.credentialsPath,
+		tokens:          make(map[string]string)}
+
+	if !lib.IsRegularFile(crd.credentialsPath) {
+		return nil
+	}
+
+	if err := crd.load(); err != nil {
+		return err
+	}
+
+	if crd.Encryption == nil {
+		for name, token := range crd.Tokens {
+			crd.tokens[name] = token
+		}
+
+		return nil
+	}
+
+	passphrase, err := lib.GetenvSecret("WALDO_CREDENTIALS_PASSPHRASE")
+
+	if err != nil {
+		return err
+	}
+
+	if len(passphrase) == 0 && passphraseFunc != nil {
+		passphrase, err = passphraseFunc() // fails if, e.g., not interactive
+
+		if errors.Is(err, lib.ErrInterrupted) {
+			return err
+		}
+	}
+
+	if len(passphrase) == 0 {
+		return errors.New("Waldo credentials are encrypted -- set WALDO_CREDENTIALS_PASSPHRASE to unlock them")
+	}
+
+	return crd.decrypt(passphrase)
+}
+
+//-----------------------------------------------------------------------------
+
+func makeAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	if iterations < 1 || iterations > crdMaxIterations {
+		return nil, fmt.Errorf("Invalid Waldo credentials KDF iterations: %d", iterations)
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key([]byte(passphrase), salt, iterations, crdKeyLength, sha256.New))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,233 @@
+package data
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCredentialsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"unencrypted", ""},
+		{"encrypted", "correct horse battery staple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("WALDO_CREDENTIALS_PASSPHRASE", tt.passphrase)
+
+			crd, err := SetupCredentials(nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			crd.SetPassphrase(tt.passphrase)
+			crd.SetToken("default", "u-0123456789abcdef0123456789abcdef")
+
+			if err := crd.Save(); err != nil {
+				t.Fatalf("Save() error = %v", err)
+			}
+
+			raw, err := os.ReadFile(crd.Path())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if encrypted := !strings.Contains(string(raw), "u-0123456789abcdef"); encrypted != (len(tt.passphrase) > 0) {
+				t.Errorf("credentials file = %q", raw)
+			}
+
+			crd, err = SetupCredentials(nil)
+
+			if err != nil {
+				t.Fatalf("SetupCredentials() error = %v", err)
+			}
+
+			if token := crd.Token("default"); token != "u-0123456789abcdef0123456789abcdef" {
+				t.Errorf("Token() = %q", token)
+			}
+
+			if crd.IsEncrypted() != (len(tt.passphrase) > 0) {
+				t.Errorf("IsEncrypted() = %v", crd.IsEncrypted())
+			}
+		})
+	}
+}
+
+func TestCredentialsWrongPassphrase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("WALDO_CREDENTIALS_PASSPHRASE", "")
+
+	crd, err := SetupCredentials(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	crd.SetPassphrase("right")
+	crd.SetToken("default", "u-0123456789abcdef0123456789abcdef")
+
+	if err := crd.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SetupCredentials(nil); err == nil || !strings.Contains(err.Error(), "WALDO_CREDENTIALS_PASSPHRASE") {
+		t.Errorf("SetupCredentials() without passphrase error = %v", err)
+	}
+
+	passphraseFunc := func() (string, error) { return "wrong", nil }
+
+	if _, err := SetupCredentials(passphraseFunc); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("SetupCredentials() with wrong passphrase error = %v", err)
+	}
+
+	passphraseFunc = func() (string, error) { return "", errors.New("not interactive") }
+
+	if _, err := SetupCredentials(passphraseFunc); err == nil || !strings.Contains(err.Error(), "WALDO_CREDENTIALS_PASSPHRASE") {
+		t.Errorf("SetupCredentials() not interactive error = %v", err)
+	}
+
+	t.Setenv("WALDO_CREDENTIALS_PASSPHRASE", "right")
+
+	if crd, err := SetupCredentials(nil); err != nil || crd.Token("default") == "" {
+		t.Errorf("SetupCredentials() with right passphrase error = %v", err)
+	}
+}
+
+func TestCredentialsDecryptTampered(t *testing.T) {
+	tests := []struct {
+		name    string
+		tamper  func(enc *CredentialsEncryption)
+		wantErr string
+	}{
+		{
+			name:    "short nonce",
+			tamper:  func(enc *CredentialsEncryption) { enc.Nonce = base64.StdEncoding.EncodeToString([]byte("short")) },
+			wantErr: "nonce length"},
+		{
+			name:    "excessive iterations",
+			tamper:  func(enc *CredentialsEncryption) { enc.Iterations = crdMaxIterations + 1 },
+			wantErr: "KDF iterations"},
+		{
+			name:    "no iterations",
+			tamper:  func(enc *CredentialsEncryption) { enc.Iterations = 0 },
+			wantErr: "KDF iterations"},
+		{
+			name:    "unknown cipher",
+			tamper:  func(enc *CredentialsEncryption) { enc.Cipher = "rot13" },
+			wantErr: "Unsupported"},
+		{
+			name: "modified ciphertext",
+			tamper: func(enc *CredentialsEncryption) {
+				ciphertext, _ := base64.StdEncoding.DecodeString(enc.Ciphertext)
+				ciphertext[0] ^= 0xff
+				enc.Ciphertext = base64.StdEncoding.EncodeToString(ciphertext)
+			},
+			wantErr: "wrong passphrase"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crd := &Credentials{
+				passphrase: "secret",
+				tokens:     map[string]string{"default": "u-0123456789abcdef0123456789abcdef"}}
+
+			if err := crd.encrypt(); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.tamper(crd.Encryption)
+
+			if err := crd.decrypt("secret"); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("decrypt() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCredentialsUpdateConcurrent(t *testing.T) {
+	tests := []struct {
+		name       string
+		passphrase string
+	}{
+		{"unencrypted", ""},
+		{"encrypted", "correct horse battery staple"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("WALDO_CREDENTIALS_PASSPHRASE", tt.passphrase)
+
+			crd, err := SetupCredentials(nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := crd.Update(func(crd *Credentials) { crd.SetPassphrase(tt.passphrase) }); err != nil {
+				t.Fatal(err)
+			}
+
+			//
+			// Every credentials object is loaded before any of them is
+			// updated, as with concurrent “auth” verbs:
+			//
+			names := []string{"a", "b", "c", "d"}
+			crds := make([]*Credentials, len(names))
+
+			for idx := range names {
+				if crds[idx], err = SetupCredentials(nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var wg sync.WaitGroup
+
+			errs := make([]error, len(names))
+
+			for idx, name := range names {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					errs[idx] = crds[idx].Update(func(crd *Credentials) {
+						crd.SetToken(name, "u-token-"+name)
+					})
+				}()
+			}
+
+			wg.Wait()
+
+			if err := errors.Join(errs...); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+
+			crd, err = SetupCredentials(nil)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, name := range names {
+				if token := crd.Token(name); token != "u-token-"+name {
+					t.Errorf("Token(%q) = %q", name, token)
+				}
+			}
+
+			if crd.IsEncrypted() != (len(tt.passphrase) > 0) {
+				t.Errorf("IsEncrypted() = %v", crd.IsEncrypted())
+			}
+		})
+	}
+}
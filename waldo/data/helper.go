@@ -0,0 +1,111 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// CredentialHelper runs an external command to get, store, or erase API
+// tokens, much like a git credential helper. The command is run with the
+// action (“get”, “store”, or “erase”) as its last argument, and exchanges
+// key=value lines (terminated by a blank line or end of input) on stdin and
+// stdout:
+//
+//	protocol=waldo
+//	profile=<name>
+//	token=<api-token>   (sent for “store”; expected back for “get”)
+type CredentialHelper struct {
+	command string
+}
+
+//-----------------------------------------------------------------------------
+
+func NewCredentialHelper(command string) *CredentialHelper {
+	return &CredentialHelper{command: command}
+}
+
+//-----------------------------------------------------------------------------
+
+func (ch *CredentialHelper) Command() string {
+	return ch.command
+}
+
+func (ch *CredentialHelper) Erase(profileName string) error {
+	_, err := ch.run("erase", map[string]string{"profile": profileName})
+
+	return err
+}
+
+func (ch *CredentialHelper) Get(profileName string) (string, error) {
+	attrs, err := ch.run("get", map[string]string{"profile": profileName})
+
+	if err != nil {
+		return "", err
+	}
+
+	return attrs["token"], nil
+}
+
+func (ch *CredentialHelper) Store(profileName, token string) error {
+	_, err := ch.run("store", map[string]string{"profile": profileName, "token": token})
+
+	return err
+}
+
+//-----------------------------------------------------------------------------
+
+func (ch *CredentialHelper) run(action string, attrs map[string]string) (map[string]string, error) {
+	//
+	// The command is split on whitespace (no shell quoting) so as not to
+	// depend on a particular shell:
+	//
+	fields := strings.Fields(ch.command)
+
+	if len(fields) == 0 {
+		return nil, errors.New("No credential helper specified")
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("protocol=waldo\n")
+
+	for _, key := range lib.SortedKeys(attrs) {
+		fmt.Fprintf(&sb, "%v=%v\n", key, attrs[key])
+	}
+
+	sb.WriteString("\n")
+
+	task := lib.NewTask(fields[0], append(fields[1:], action)...)
+
+	task.Env = lib.CurrentEnvironment()
+	task.IOStreams = lib.NewIOStreams(strings.NewReader(sb.String()), nil, nil)
+
+	stdout, stderr, err := task.Run()
+
+	if err != nil {
+		if len(stderr) > 0 {
+			return nil, fmt.Errorf("Credential helper %q failed to %v, error: %v", fields[0], action, stderr)
+		}
+
+		return nil, fmt.Errorf("Credential helper %q failed to %v, error: %v", fields[0], action, err)
+	}
+
+	result := make(map[string]string)
+
+	for _, line := range strings.Split(stdout, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if len(line) == 0 {
+			break
+		}
+
+		if key, value, found := strings.Cut(line, "="); found {
+			result[key] = value
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeTestHelper writes a stand-in credential helper that records its
+// arguments and input, then prints the given output and exits with the given
+// status.
+func writeTestHelper(t *testing.T, output string, status int) (string, string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in credential helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	argsPath := filepath.Join(dir, "args.txt")
+	inputPath := filepath.Join(dir, "input.txt")
+	outputPath := filepath.Join(dir, "output.txt")
+	path := filepath.Join(dir, "helper")
+
+	if err := os.WriteFile(outputPath, []byte(output), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := `#!/bin/sh
+printf '%s\n' "$@" > "` + argsPath + `"
+cat > "` + inputPath + `"
+cat "` + outputPath + `"
+[ ` + strconv.Itoa(status) + ` -eq 0 ] || echo "vault is sealed" >&2
+exit ` + strconv.Itoa(status) + `
+`
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, argsPath, inputPath
+}
+
+func TestCredentialHelper(t *testing.T) {
+	tests := []struct {
+		name      string
+		call      func(ch *CredentialHelper) (string, error)
+		output    string
+		status    int
+		wantArgs  string
+		wantInput string
+		wantToken string
+		wantErr   string
+	}{
+		{
+			name:      "get",
+			call:      func(ch *CredentialHelper) (string, error) { return ch.Get("work") },
+			output:    "protocol=waldo\nprofile=work\ntoken=u-0123456789abcdef\n",
+			wantArgs:  "--vault\nget\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n",
+			wantToken: "u-0123456789abcdef"},
+		{
+			name:      "get with CRLF",
+			call:      func(ch *CredentialHelper) (string, error) { return ch.Get("work") },
+			output:    "token=u-0123456789abcdef\r\nprofile=work\r\n",
+			wantArgs:  "--vault\nget\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n",
+			wantToken: "u-0123456789abcdef"},
+		{
+			name:      "get stops at blank line",
+			call:      func(ch *CredentialHelper) (string, error) { return ch.Get("work") },
+			output:    "profile=work\n\ntoken=u-ignored\n",
+			wantArgs:  "--vault\nget\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n"},
+		{
+			name:      "get without token",
+			call:      func(ch *CredentialHelper) (string, error) { return ch.Get("work") },
+			output:    "protocol=waldo\nnot a key-value line\n",
+			wantArgs:  "--vault\nget\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n"},
+		{
+			name:      "store",
+			call:      func(ch *CredentialHelper) (string, error) { return "", ch.Store("work", "u-0123456789abcdef") },
+			wantArgs:  "--vault\nstore\n",
+			wantInput: "protocol=waldo\nprofile=work\ntoken=u-0123456789abcdef\n\n"},
+		{
+			name:      "erase",
+			call:      func(ch *CredentialHelper) (string, error) { return "", ch.Erase("work") },
+			wantArgs:  "--vault\nerase\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n"},
+		{
+			name:      "failure",
+			call:      func(ch *CredentialHelper) (string, error) { return ch.Get("work") },
+			output:    "token=u-0123456789abcdef\n",
+			status:    3,
+			wantArgs:  "--vault\nget\n",
+			wantInput: "protocol=waldo\nprofile=work\n\n",
+			wantErr:   "failed to get, error: vault is sealed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, argsPath, inputPath := writeTestHelper(t, tt.output, tt.status)
+
+			token, err := tt.call(NewCredentialHelper(path + "  --vault"))
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("error = %v", err)
+			}
+
+			if token != tt.wantToken {
+				t.Errorf("token = %q, want %q", token, tt.wantToken)
+			}
+
+			if args, _ := os.ReadFile(argsPath); string(args) != tt.wantArgs {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+
+			if input, _ := os.ReadFile(inputPath); string(input) != tt.wantInput {
+				t.Errorf("input = %q, want %q", input, tt.wantInput)
+			}
+		})
+	}
+}
+
+func TestCredentialHelperNoCommand(t *testing.T) {
+	if _, err := NewCredentialHelper("  ").Get("work"); err == nil || err.Error() != "No credential helper specified" {
+		t.Errorf("Get() error = %v", err)
+	}
+}
+
+// writeTestStoringHelper writes a stand-in credential helper that appends its
+// input to a log on every call.
+func writeTestStoringHelper(t *testing.T) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("stand-in credential helper is a shell script")
+	}
+
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "log.txt")
+	path := filepath.Join(dir, "helper")
+
+	script := "#!/bin/sh\ncat >> \"" + logPath + "\"\n"
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	return path, logPath
+}
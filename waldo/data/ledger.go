@@ -0,0 +1,124 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/tpw"
+)
+
+const (
+	ldgFormatVersion = 1
+)
+
+//-----------------------------------------------------------------------------
+
+type Ledger struct {
+	FormatVersion int                     `yaml:"format_version"`
+	Entries       map[string]*LedgerEntry `yaml:"entries,omitempty"`
+
+	dirty      bool
+	ledgerPath string // absolute
+}
+
+type LedgerEntry struct {
+	BuildVersion string    `yaml:"build_version,omitempty"`
+	GitCommit    string    `yaml:"git_commit,omitempty"`
+	UploadedAt   time.Time `yaml:"uploaded_at"`
+}
+
+//-----------------------------------------------------------------------------
+
+func MakeLedgerKey(appID, uploadToken, variantName string) string {
+	appKey := appID
+
+	if len(appKey) == 0 {
+		hash := sha256.Sum256([]byte(uploadToken))
+
+		appKey = "ci-" + hex.EncodeToString(hash[:6])
+	}
+
+	if len(variantName) == 0 {
+		return appKey
+	}
+
+	return appKey + "/" + strings.ToLower(variantName)
+}
+
+func SetupLedger() (*Ledger, error) {
+	dataPath, err := makeHomeDataPath()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ldg := &Ledger{
+		FormatVersion: ldgFormatVersion,
+		Entries:       make(map[string]*LedgerEntry),
+		ledgerPath:    filepath.Join(dataPath, "ledger.yml")}
+
+	if lib.IsRegularFile(ldg.ledgerPath) {
+		if err := ldg.load(); err != nil {
+			return nil, err
+		}
+
+		if ldg.Entries == nil {
+			ldg.Entries = make(map[string]*LedgerEntry)
+		}
+	}
+
+	return ldg, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (ldg *Ledger) Find(key string) *LedgerEntry {
+	return ldg.Entries[key]
+}
+
+func (ldg *Ledger) Record(key string, entry *LedgerEntry) {
+	ldg.Entries[key] = entry
+
+	ldg.dirty = true
+}
+
+func (ldg *Ledger) Save() error {
+	if !ldg.dirty {
+		return nil
+	}
+
+	data, err := tpw.EncodeToYAML(ldg)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ldg.ledgerPath), 0755); err != nil {
+		return err
+	}
+
+	if err := lib.WriteFileAtomic(ldg.ledgerPath, data, 0644); err != nil {
+		return err
+	}
+
+	ldg.dirty = false
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (ldg *Ledger) load() error {
+	data, err := os.ReadFile(ldg.ledgerPath)
+
+	if err != nil {
+		return err
+	}
+
+	return tpw.DecodeFromYAML(data, ldg)
+}
@@ -2,28 +2,51 @@ package data
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/waldoapp/waldo-go-cli/lib"
 	"github.com/waldoapp/waldo-go-cli/lib/tpw"
 )
 
 const (
-	prfFormatVersion = 1
+	DefaultProfileName = "default"
+
+	prfFormatVersion = 3
+	prfLockTimeout   = 30 * time.Second
 )
 
+// ErrProfileNotFound is returned by SetupProfile when no profile exists and
+// none is to be created.
+var ErrProfileNotFound = errors.New("Waldo profile not found")
+
+// prfMigrations maps each superseded profile format version to the step that
+// migrates a profile from it to the next format version.
+var prfMigrations = map[int]func(prf *Profile) error{
+	1: (*Profile).migrateFromVersion1,
+	2: (*Profile).migrateFromVersion2}
+
 //-----------------------------------------------------------------------------
 
 type Profile struct {
-	FormatVersion int    `yaml:"format_version"`
-	APIToken      string `yaml:"user_token,omitempty"`
+	FormatVersion    int                      `yaml:"format_version"`
+	CurrentProfile   string                   `yaml:"current_profile,omitempty"`
+	CredentialHelper string                   `yaml:"credential_helper,omitempty"`
+	Profiles         map[string]*ProfileEntry `yaml:"profiles,omitempty"`
+
+	LegacyAPIToken string `yaml:"user_token,omitempty"` // format version 1
 
 	basePath    string // absolute
 	dirty       bool
 	profilePath string // absolute
 }
 
+type ProfileEntry struct {
+	LegacyAPIToken string `yaml:"user_token,omitempty"` // format version 2
+}
+
 //-----------------------------------------------------------------------------
 
 func SetupProfile(ck CreateKind) (*Profile, bool, error) {
@@ -60,7 +83,7 @@ func SetupProfile(ck CreateKind) (*Profile, bool, error) {
 		dataPath = findHomeDataPath()
 
 		if len(dataPath) == 0 {
-			return nil, false, errors.New("Waldo profile not found")
+			return nil, false, ErrProfileNotFound
 		}
 	}
 
@@ -73,12 +96,10 @@ func SetupProfile(ck CreateKind) (*Profile, bool, error) {
 			return nil, false, err
 		}
 
-		if err := prf.migrate(); err != nil {
-			return nil, false, err
-		}
-
-		if err := prf.Save(); err != nil {
-			return nil, false, err
+		if prf.FormatVersion != prfFormatVersion {
+			if err := prf.migrate(); err != nil {
+				return nil, false, err
+			}
 		}
 
 		return prf, false, nil
@@ -89,18 +110,59 @@ func SetupProfile(ck CreateKind) (*Profile, bool, error) {
 			return nil, false, err
 		}
 
-		prf.FormatVersion = prfFormatVersion
-
-		prf.MarkDirty()
+		created, err := prf.create()
 
-		if err := prf.Save(); err != nil {
+		if err != nil {
 			return nil, false, err
 		}
 
-		return prf, true, nil
+		return prf, created, nil
+	}
+
+	return nil, false, ErrProfileNotFound
+}
+
+// UpdateProfile sets up the profile (as SetupProfile does) and then applies
+// the given function to it while holding the profile lock, saving the profile
+// afterwards if the function marks it dirty. The profile is reloaded once the
+// lock is held, so that a change made by another process in the meantime is
+// not lost. The function must not itself save the profile.
+func UpdateProfile(ck CreateKind, updateFunc func(prf *Profile) error) (*Profile, error) {
+	prf, _, err := SetupProfile(ck)
+
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := lib.LockFile(prf.profilePath, prfLockTimeout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer unlock()
+
+	if err := prf.reload(); err != nil {
+		return nil, err
+	}
+
+	if prf.FormatVersion != prfFormatVersion {
+		return nil, prf.formatVersionError()
+	}
+
+	if err := updateFunc(prf); err != nil {
+		return nil, err
+	}
+
+	if !prf.IsDirty() {
+		return prf, nil
+	}
+
+	if err := prf.save(); err != nil {
+		return nil, err
 	}
 
-	return nil, false, errors.New("Waldo profile not found")
+	return prf, nil
 }
 
 //-----------------------------------------------------------------------------
@@ -109,6 +171,10 @@ func (prf *Profile) BasePath() string {
 	return prf.basePath
 }
 
+func (prf *Profile) Find(name string) *ProfileEntry {
+	return prf.Profiles[name]
+}
+
 func (prf *Profile) IsDirty() bool {
 	return prf.dirty
 }
@@ -121,24 +187,49 @@ func (prf *Profile) Path() string {
 	return prf.profilePath
 }
 
-func (prf *Profile) Save() error {
-	if !prf.IsDirty() {
-		return nil
+func (prf *Profile) Remove(name string) bool {
+	if _, found := prf.Profiles[name]; !found {
+		return false
 	}
 
-	data, err := tpw.EncodeToYAML(prf)
+	delete(prf.Profiles, name)
 
-	if err != nil {
-		return err
+	if prf.CurrentProfile == name {
+		prf.CurrentProfile = ""
 	}
 
-	if err := os.WriteFile(prf.profilePath, data, 0644); err != nil {
-		return err
+	prf.MarkDirty()
+
+	return true
+}
+
+// ResolveName returns the name of the profile to use: the given name (from
+// the --profile option) if any, else WALDO_PROFILE, else the current profile
+// (as set by “waldo profile use”), else the default profile.
+func (prf *Profile) ResolveName(name string) string {
+	if len(name) > 0 {
+		return name
 	}
 
-	prf.dirty = false
+	if name = os.Getenv("WALDO_PROFILE"); len(name) > 0 {
+		return name
+	}
 
-	return nil
+	if prf != nil && len(prf.CurrentProfile) > 0 {
+		return prf.CurrentProfile
+	}
+
+	return DefaultProfileName
+}
+
+func (prf *Profile) Set(name string, entry *ProfileEntry) {
+	if prf.Profiles == nil {
+		prf.Profiles = make(map[string]*ProfileEntry)
+	}
+
+	prf.Profiles[name] = entry
+
+	prf.MarkDirty()
 }
 
 //-----------------------------------------------------------------------------
@@ -172,6 +263,61 @@ func makeHomeDataPath() (string, error) {
 
 //-----------------------------------------------------------------------------
 
+// backup copies the profile file as it is before migration alongside it,
+// readable only by its owner (as it may hold API tokens in plain text).
+func (prf *Profile) backup() error {
+	data, err := os.ReadFile(prf.profilePath)
+
+	if err != nil {
+		return err
+	}
+
+	backupPath := fmt.Sprintf("%v.v%d.bak", prf.profilePath, prf.FormatVersion)
+
+	return lib.WriteFileAtomic(backupPath, data, 0600)
+}
+
+// create saves a new, empty profile, reporting whether it did so. If another
+// process created one while this one was waiting for the lock, it is loaded
+// instead (and migrated, if need be) rather than overwritten.
+func (prf *Profile) create() (bool, error) {
+	unlock, err := lib.LockFile(prf.profilePath, prfLockTimeout)
+
+	if err != nil {
+		return false, err
+	}
+
+	if lib.IsRegularFile(prf.profilePath) {
+		unlock()
+
+		if err := prf.load(); err != nil {
+			return false, err
+		}
+
+		if prf.FormatVersion != prfFormatVersion {
+			return false, prf.migrate()
+		}
+
+		return false, nil
+	}
+
+	defer unlock()
+
+	prf.FormatVersion = prfFormatVersion
+
+	prf.MarkDirty()
+
+	return true, prf.save()
+}
+
+func (prf *Profile) formatVersionError() error {
+	if prf.FormatVersion > prfFormatVersion {
+		return fmt.Errorf("Waldo profile %q has format version %d, but this version of Waldo CLI only supports up to format version %d -- upgrade Waldo CLI", prf.profilePath, prf.FormatVersion, prfFormatVersion)
+	}
+
+	return fmt.Errorf("Waldo profile %q has unexpected format version %d", prf.profilePath, prf.FormatVersion)
+}
+
 func (prf *Profile) load() error {
 	data, err := os.ReadFile(prf.profilePath)
 
@@ -183,15 +329,144 @@ func (prf *Profile) load() error {
 }
 
 func (prf *Profile) migrate() error {
-	if prf.FormatVersion < prfFormatVersion {
-		// handle any necessary migration here
+	unlock, err := lib.LockFile(prf.profilePath, prfLockTimeout)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	//
+	// Reload the profile in case another process migrated it while this one
+	// was waiting for the lock:
+	//
+	if err := prf.reload(); err != nil {
+		return err
+	}
+
+	//
+	// Refuse outright to touch a profile written by a newer version of Waldo
+	// CLI, rather than silently dropping whatever it does not understand:
+	//
+	if prf.FormatVersion > prfFormatVersion {
+		return prf.formatVersionError()
+	}
+
+	if prf.FormatVersion == prfFormatVersion {
+		return nil
+	}
+
+	if err := prf.backup(); err != nil {
+		return fmt.Errorf("Unable to back up Waldo profile, error: %v", err)
+	}
+
+	for prf.FormatVersion < prfFormatVersion {
+		step, found := prfMigrations[prf.FormatVersion]
+
+		if !found {
+			return fmt.Errorf("Unable to migrate Waldo profile from unknown format version %d", prf.FormatVersion)
+		}
+
+		if err := step(prf); err != nil {
+			return fmt.Errorf("Unable to migrate Waldo profile from format version %d, error: %v", prf.FormatVersion, err)
+		}
+
+		prf.FormatVersion++
+	}
+
+	prf.MarkDirty()
 
-		prf.FormatVersion = prfFormatVersion
+	return prf.save()
+}
+
+// migrateFromVersion1 moves the single API token held by format version 1 to
+// the default profile.
+func (prf *Profile) migrateFromVersion1() error {
+	if len(prf.LegacyAPIToken) > 0 {
+		if prf.Find(DefaultProfileName) == nil {
+			prf.Set(DefaultProfileName, &ProfileEntry{LegacyAPIToken: prf.LegacyAPIToken})
+		}
 
-		prf.MarkDirty()
-	} else if prf.FormatVersion > prfFormatVersion {
-		// may not understand newer format
+		prf.LegacyAPIToken = ""
 	}
 
 	return nil
 }
+
+// migrateFromVersion2 moves the API tokens held in plain text by format
+// version 2 to the credentials store (or credential helper).
+func (prf *Profile) migrateFromVersion2() error {
+	return prf.migrateTokens()
+}
+
+func (prf *Profile) migrateTokens() error {
+	command := os.Getenv("WALDO_CREDENTIAL_HELPER")
+
+	if len(command) == 0 {
+		command = prf.CredentialHelper
+	}
+
+	tokens := make(map[string]string)
+
+	for name, entry := range prf.Profiles {
+		if len(entry.LegacyAPIToken) == 0 {
+			continue
+		}
+
+		if len(command) > 0 {
+			if err := NewCredentialHelper(command).Store(name, entry.LegacyAPIToken); err != nil {
+				return err
+			}
+
+			entry.LegacyAPIToken = ""
+
+			continue
+		}
+
+		tokens[name] = entry.LegacyAPIToken
+
+		entry.LegacyAPIToken = ""
+	}
+
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	crd, err := SetupCredentials(nil)
+
+	if err != nil {
+		return err
+	}
+
+	return crd.Update(func(crd *Credentials) {
+		for name, token := range tokens {
+			crd.SetToken(name, token)
+		}
+	})
+}
+
+// reload discards any unsaved changes and loads the profile afresh.
+func (prf *Profile) reload() error {
+	*prf = Profile{
+		basePath:    prf.basePath,
+		profilePath: prf.profilePath}
+
+	return prf.load()
+}
+
+func (prf *Profile) save() error {
+	data, err := tpw.EncodeToYAML(prf)
+
+	if err != nil {
+		return err
+	}
+
+	if err := lib.WriteFileAtomic(prf.profilePath, data, 0644); err != nil {
+		return err
+	}
+
+	prf.dirty = false
+
+	return nil
+}
@@ -0,0 +1,252 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSetupProfileMigrate(t *testing.T) {
+	const (
+		personalToken = "u-fedcba9876543210fedcba9876543210"
+		workToken     = "u-0123456789abcdef0123456789abcdef"
+	)
+
+	versions := []struct {
+		name       string
+		profile    string
+		wantTokens map[string]string
+	}{
+		{
+			name:       "v1",
+			profile:    "format_version: 1\nuser_token: " + workToken + "\n",
+			wantTokens: map[string]string{DefaultProfileName: workToken}},
+		{
+			name:       "v2",
+			profile:    "format_version: 2\ncurrent_profile: work\nprofiles:\n  personal:\n    user_token: " + personalToken + "\n  work:\n    user_token: " + workToken + "\n",
+			wantTokens: map[string]string{"personal": personalToken, "work": workToken}},
+	}
+
+	for _, version := range versions {
+		for _, useHelper := range []bool{false, true} {
+			name := version.name + "/credentials store"
+
+			if useHelper {
+				name = version.name + "/credential helper"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				homePath := t.TempDir()
+				profilePath := filepath.Join(homePath, ".waldo", "profile.yml")
+
+				t.Setenv("HOME", homePath)
+				t.Setenv("WALDO_CREDENTIALS_PASSPHRASE", "")
+				t.Setenv("WALDO_CREDENTIAL_HELPER", "")
+
+				var logPath string
+
+				if useHelper {
+					var helperPath string
+
+					helperPath, logPath = writeTestStoringHelper(t)
+
+					t.Setenv("WALDO_CREDENTIAL_HELPER", helperPath)
+				}
+
+				if err := os.MkdirAll(filepath.Dir(profilePath), 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.WriteFile(profilePath, []byte(version.profile), 0644); err != nil {
+					t.Fatal(err)
+				}
+
+				prf, _, err := SetupProfile(CreateKindNever)
+
+				if err != nil {
+					t.Fatalf("SetupProfile() error = %v", err)
+				}
+
+				if prf.FormatVersion != prfFormatVersion {
+					t.Errorf("FormatVersion = %d, want %d", prf.FormatVersion, prfFormatVersion)
+				}
+
+				for name := range version.wantTokens {
+					if prf.Find(name) == nil {
+						t.Errorf("Find(%q) = nil", name)
+					}
+				}
+
+				//
+				// No token is left in the profile itself:
+				//
+				migrated, err := os.ReadFile(profilePath)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if strings.Contains(string(migrated), "u-") {
+					t.Errorf("migrated profile = %q", migrated)
+				}
+
+				//
+				// The profile as it was is backed up, readable only by its
+				// owner:
+				//
+				backupPath := profilePath + "." + version.name + ".bak"
+
+				backup, err := os.ReadFile(backupPath)
+
+				if err != nil || string(backup) != version.profile {
+					t.Errorf("backup = %q, %v", backup, err)
+				}
+
+				if fi, err := os.Stat(backupPath); err != nil {
+					t.Error(err)
+				} else if runtime.GOOS != "windows" && fi.Mode().Perm() != 0600 {
+					t.Errorf("backup mode = %v, want 0600", fi.Mode().Perm())
+				}
+
+				if useHelper {
+					log, err := os.ReadFile(logPath)
+
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					for name, token := range version.wantTokens {
+						if want := "protocol=waldo\nprofile=" + name + "\ntoken=" + token + "\n"; !strings.Contains(string(log), want) {
+							t.Errorf("helper input = %q, want %q", log, want)
+						}
+					}
+
+					if _, err := os.Stat(filepath.Join(homePath, ".waldo", "credentials.yml")); err == nil {
+						t.Errorf("credentials store written despite credential helper")
+					}
+
+					return
+				}
+
+				crd, err := SetupCredentials(nil)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				for name, token := range version.wantTokens {
+					if got := crd.Token(name); got != token {
+						t.Errorf("Token(%q) = %q, want %q", name, got, token)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSetupProfileNewerFormat(t *testing.T) {
+	homePath := t.TempDir()
+	profilePath := filepath.Join(homePath, ".waldo", "profile.yml")
+	profile := "format_version: 99\nprofiles:\n  work:\n    future_setting: true\n"
+
+	t.Setenv("HOME", homePath)
+
+	if err := os.MkdirAll(filepath.Dir(profilePath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(profilePath, []byte(profile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := SetupProfile(CreateKindNever); err == nil || !strings.Contains(err.Error(), "upgrade Waldo CLI") {
+		t.Errorf("SetupProfile() error = %v, want upgrade", err)
+	}
+
+	if _, err := UpdateProfile(CreateKindIfNeeded, func(prf *Profile) error { return nil }); err == nil || !strings.Contains(err.Error(), "upgrade Waldo CLI") {
+		t.Errorf("UpdateProfile() error = %v, want upgrade", err)
+	}
+
+	if data, err := os.ReadFile(profilePath); err != nil || string(data) != profile {
+		t.Errorf("profile = %q, %v, want unchanged", data, err)
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(homePath, ".waldo", "*")); len(matches) != 1 {
+		t.Errorf("files = %v, want profile only", matches)
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("WALDO_CREDENTIAL_HELPER", "")
+
+	if _, err := UpdateProfile(CreateKindNever, func(prf *Profile) error { return nil }); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("UpdateProfile() without profile error = %v", err)
+	}
+
+	//
+	// Concurrent updates must not lose one another's changes:
+	//
+	const count = 8
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, count)
+
+	for idx := 0; idx < count; idx++ {
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			_, err := UpdateProfile(CreateKindIfNeeded, func(prf *Profile) error {
+				prf.Set(name, &ProfileEntry{})
+
+				return nil
+			})
+
+			errs <- err
+		}(fmt.Sprintf("profile-%d", idx))
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateProfile() error = %v", err)
+		}
+	}
+
+	prf, _, err := SetupProfile(CreateKindNever)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(prf.Profiles) != count {
+		t.Errorf("Profiles = %v, want %d entries", prf.Profiles, count)
+	}
+
+	//
+	// A failed update is not saved:
+	//
+	errUpdate := errors.New("update failed")
+
+	if _, err := UpdateProfile(CreateKindNever, func(prf *Profile) error {
+		prf.Remove("profile-0")
+
+		return errUpdate
+	}); err != errUpdate {
+		t.Fatalf("UpdateProfile() error = %v, want %v", err, errUpdate)
+	}
+
+	if prf, _, _ = SetupProfile(CreateKindNever); prf.Find("profile-0") == nil {
+		t.Errorf("failed update was saved")
+	}
+}
@@ -4,14 +4,31 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 var (
-	apiTokenRE = regexp.MustCompile(`^u-[0-9a-fA-F]+$`)
-	appIDRE    = regexp.MustCompile(`^app-[0-9a-fA-F]+$`)
-	ciTokenRE  = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	apiTokenRE    = regexp.MustCompile(`^u-[0-9a-fA-F]+$`)
+	appIDRE       = regexp.MustCompile(`^app-[0-9a-fA-F]+$`)
+	ciTokenRE     = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	metadataKeyRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	profileNameRE = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
 )
 
+func ParseMetadataItem(item string) (string, string, error) {
+	key, value, found := strings.Cut(item, "=")
+
+	if !found {
+		return "", "", fmt.Errorf("Invalid metadata syntax (expected key=value): %q", item)
+	}
+
+	if err := ValidateMetadataKey(key); err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
 func ValidateAPIToken(token string) error {
 	if len(token) == 0 {
 		return errors.New("No API token specified")
@@ -48,6 +65,30 @@ func ValidateCIToken(token string) error {
 	return nil
 }
 
+func ValidateMetadataKey(key string) error {
+	if len(key) == 0 {
+		return errors.New("No metadata key specified")
+	}
+
+	if !metadataKeyRE.MatchString(key) {
+		return fmt.Errorf("Invalid metadata key syntax: %q", key)
+	}
+
+	return nil
+}
+
+func ValidateProfileName(name string) error {
+	if len(name) == 0 {
+		return errors.New("No profile name specified")
+	}
+
+	if !profileNameRE.MatchString(name) {
+		return fmt.Errorf("Invalid profile name syntax: %q", name)
+	}
+
+	return nil
+}
+
 func ValidateUploadToken(token string) error {
 	if len(token) == 0 {
 		return errors.New("No upload token specified")
@@ -0,0 +1,243 @@
+package waldo
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/artifact"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+type DiffBuildsOptions struct {
+	Format       string
+	NewBuildPath string
+	OldBuildPath string
+	Verbose      bool
+}
+
+type DiffBuildsAction struct {
+	ioStreams *lib.IOStreams
+	options   *DiffBuildsOptions
+
+	cleanups []func()
+}
+
+//-----------------------------------------------------------------------------
+
+func NewDiffBuildsAction(options *DiffBuildsOptions, ioStreams *lib.IOStreams) *DiffBuildsAction {
+	return &DiffBuildsAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+//-----------------------------------------------------------------------------
+
+func (dba *DiffBuildsAction) Perform() error {
+	format, err := dba.detectFormat()
+
+	if err != nil {
+		return err
+	}
+
+	defer dba.cleanup()
+
+	oldInfo, err := dba.inspectBuild(dba.options.OldBuildPath, format)
+
+	if err != nil {
+		return err
+	}
+
+	newInfo, err := dba.inspectBuild(dba.options.NewBuildPath, format)
+
+	if err != nil {
+		return err
+	}
+
+	delta := artifact.Diff(oldInfo, newInfo)
+
+	if format == "json" {
+		return dba.emitJSON(delta)
+	}
+
+	dba.emitText(delta)
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (dba *DiffBuildsAction) cleanup() {
+	for idx := len(dba.cleanups) - 1; idx >= 0; idx-- {
+		dba.cleanups[idx]()
+	}
+
+	dba.cleanups = nil
+}
+
+func (dba *DiffBuildsAction) detectFormat() (string, error) {
+	switch format := strings.ToLower(dba.options.Format); format {
+	case "":
+		return "text", nil
+
+	case "json", "text":
+		return format, nil
+
+	default:
+		return "", fmt.Errorf("Invalid output format: %q", dba.options.Format)
+	}
+}
+
+func (dba *DiffBuildsAction) emitJSON(delta *artifact.Delta) error {
+	output, err := json.MarshalIndent(delta, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	dba.ioStreams.Printf("%s\n", output)
+
+	return nil
+}
+
+func (dba *DiffBuildsAction) emitList(title string, added, removed []string) {
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	dba.ioStreams.Printf("\n%v:\n", title)
+
+	for _, value := range added {
+		dba.ioStreams.Printf("  + %v\n", value)
+	}
+
+	for _, value := range removed {
+		dba.ioStreams.Printf("  - %v\n", value)
+	}
+}
+
+func (dba *DiffBuildsAction) emitText(delta *artifact.Delta) {
+	dba.ioStreams.Printf("\nComparing %q with %q\n", dba.options.OldBuildPath, dba.options.NewBuildPath)
+
+	if !delta.HasChanges() {
+		dba.ioStreams.Printf("\nNo differences found\n")
+
+		return
+	}
+
+	if len(delta.MetadataChanges) > 0 {
+		dba.ioStreams.Printf("\nMetadata:\n")
+
+		for _, change := range delta.MetadataChanges {
+			dba.ioStreams.Printf("  %v: %q -> %q\n", change.Field, change.OldValue, change.NewValue)
+		}
+	}
+
+	dba.ioStreams.Printf("\nTotal size: %v -> %v (%v)\n",
+		formatSize(delta.Old.TotalSize),
+		formatSize(delta.New.TotalSize),
+		formatSizeChange(delta.SizeChange))
+
+	if len(delta.AddedFiles) > 0 || len(delta.RemovedFiles) > 0 || len(delta.ResizedFiles) > 0 {
+		dba.ioStreams.Printf("\nFiles (%d added, %d removed, %d resized):\n",
+			len(delta.AddedFiles),
+			len(delta.RemovedFiles),
+			len(delta.ResizedFiles))
+
+		for _, change := range delta.AddedFiles {
+			dba.ioStreams.Printf("  + %v (%v)\n", change.Path, formatSize(change.NewSize))
+		}
+
+		for _, change := range delta.RemovedFiles {
+			dba.ioStreams.Printf("  - %v (%v)\n", change.Path, formatSize(change.OldSize))
+		}
+
+		for _, change := range delta.ResizedFiles {
+			dba.ioStreams.Printf("  ~ %v (%v -> %v, %v)\n",
+				change.Path,
+				formatSize(change.OldSize),
+				formatSize(change.NewSize),
+				formatSizeChange(change.NewSize-change.OldSize))
+		}
+	}
+
+	dba.emitList("Frameworks/native libraries", delta.AddedFrameworks, delta.RemovedFrameworks)
+	dba.emitList("Permissions", delta.AddedPermissions, delta.RemovedPermissions)
+	dba.emitList("Entitlements", delta.AddedEntitlements, delta.RemovedEntitlements)
+}
+
+func (dba *DiffBuildsAction) inspectBuild(sourcePath, format string) (*artifact.Info, error) {
+	if len(sourcePath) == 0 {
+		return nil, fmt.Errorf("No build path specified")
+	}
+
+	if err := validateBuildPath(sourcePath); err != nil {
+		return nil, err
+	}
+
+	cfg, err := data.LoadConfiguration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ioStreams := dba.ioStreams
+
+	if format == "json" {
+		ioStreams = lib.NewIOStreams(nil, io.Discard, io.Discard)
+	}
+
+	u := artifact.NewUnpacker(
+		sourcePath,
+		findBundletoolPath(cfg),
+		dba.options.Verbose,
+		ioStreams)
+
+	dba.cleanups = append(dba.cleanups, u.Cleanup)
+
+	buildPath, err := u.Unpack()
+
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := artifact.Inspect(buildPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	info.Path = sourcePath
+
+	return info, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func formatSize(size int64) string {
+	value := float64(size)
+
+	for _, unit := range []string{"B", "KB", "MB", "GB"} {
+		if value < 1024 || unit == "GB" {
+			if unit == "B" {
+				return fmt.Sprintf("%d B", size)
+			}
+
+			return fmt.Sprintf("%.1f %v", value, unit)
+		}
+
+		value /= 1024
+	}
+
+	return ""
+}
+
+func formatSizeChange(delta int64) string {
+	if delta < 0 {
+		return "-" + formatSize(-delta)
+	}
+
+	return "+" + formatSize(delta)
+}
@@ -0,0 +1,198 @@
+package waldo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+	"github.com/waldoapp/waldo-go-cli/waldo/artifact"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+type easAppConfig struct {
+	Expo struct {
+		Extra struct {
+			EAS struct {
+				ProjectID string `json:"projectId"`
+			} `json:"eas"`
+		} `json:"extra"`
+	} `json:"expo"`
+}
+
+//-----------------------------------------------------------------------------
+
+func (ua *UploadAction) detectEASPlatform() (lib.Platform, error) {
+	value := ua.options.EASPlatform
+
+	if len(value) == 0 {
+		return lib.PlatformUnknown, nil
+	}
+
+	switch platform := lib.ParsePlatform(value); platform {
+	case lib.PlatformAndroid, lib.PlatformIos:
+		return platform, nil
+
+	default:
+		return lib.PlatformUnknown, fmt.Errorf("Invalid EAS platform: %q", value)
+	}
+}
+
+func (ua *UploadAction) detectEASProjectID() (string, error) {
+	if projectID := os.Getenv("WALDO_EAS_PROJECT_ID"); len(projectID) > 0 {
+		return projectID, nil
+	}
+
+	data, err := os.ReadFile("app.json")
+
+	if err != nil {
+		return "", errors.New("Unable to determine EAS project ID -- no app.json found (set WALDO_EAS_PROJECT_ID instead)")
+	}
+
+	cfg := &easAppConfig{}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return "", fmt.Errorf("Unable to parse app.json, error: %v", err)
+	}
+
+	if projectID := cfg.Expo.Extra.EAS.ProjectID; len(projectID) > 0 {
+		return projectID, nil
+	}
+
+	return "", errors.New("Unable to determine EAS project ID -- no expo.extra.eas.projectId in app.json (set WALDO_EAS_PROJECT_ID instead)")
+}
+
+func (ua *UploadAction) fetchEASBuild() (string, error) {
+	if ua.options.FromEAS == "local" {
+		platform, err := ua.detectEASPlatform()
+
+		if err != nil {
+			return "", err
+		}
+
+		return findLocalEASBuild(platform)
+	}
+
+	bf := api.NewBuildFetcher(
+		ua.easBuild.ArtifactURL(),
+		nil,
+		"",
+		artifact.MakeExtractLimits(),
+		data.CLIPrefix,
+		ua.options.Verbose,
+		ua.ioStreams)
+
+	ua.cleanups = append(ua.cleanups, bf.Cleanup)
+
+	return bf.Fetch()
+}
+
+// resolveEASBuild looks up a remote EAS build (but does not fetch its
+// artifact yet), so that its variant name and git commit are known before the
+// project policy is checked.
+func (ua *UploadAction) resolveEASBuild() error {
+	if fromEAS := ua.options.FromEAS; len(fromEAS) == 0 || fromEAS == "local" {
+		return nil
+	}
+
+	platform, err := ua.detectEASPlatform()
+
+	if err != nil {
+		return err
+	}
+
+	expoToken := os.Getenv("EXPO_TOKEN")
+
+	var (
+		eb        *api.EASBuild
+		projectID string
+	)
+
+	if ua.options.FromEAS == "latest" {
+		projectID, err = ua.detectEASProjectID()
+
+		if err != nil {
+			return err
+		}
+
+		ua.ioStreams.Printf("\nFetching latest EAS build for project %q\n", projectID)
+
+		eb, err = api.FetchLatestEASBuild(projectID, platform, expoToken, ua.options.Verbose, ua.ioStreams)
+	} else {
+		ua.ioStreams.Printf("\nFetching EAS build %q\n", ua.options.FromEAS)
+
+		eb, err = api.FetchEASBuild(ua.options.FromEAS, expoToken, ua.options.Verbose, ua.ioStreams)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if platform != lib.PlatformUnknown && eb.ParsePlatform() != platform {
+		return fmt.Errorf("EAS build %q is not an %v build", eb.BuildID, platform)
+	}
+
+	if eb.Status != "FINISHED" {
+		return fmt.Errorf("EAS build %q is not finished (status: %v)", eb.BuildID, eb.Status)
+	}
+
+	if len(eb.ArtifactURL()) == 0 { // e.g., expired
+		return fmt.Errorf("EAS build %q has no downloadable artifact", eb.BuildID)
+	}
+
+	if !eb.IsSimulatorBuild() {
+		return fmt.Errorf("EAS build %q is not a simulator/emulator build", eb.BuildID)
+	}
+
+	if len(ua.options.VariantName) == 0 {
+		ua.options.VariantName = eb.BuildProfile
+	}
+
+	if len(ua.options.GitCommit) == 0 {
+		ua.options.GitCommit = eb.GitCommitHash
+	}
+
+	ua.easBuild = eb
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func findLocalEASBuild(platform lib.Platform) (string, error) {
+	var patterns []string
+
+	if platform != lib.PlatformAndroid {
+		patterns = append(patterns, "build-*.tar.gz")
+	}
+
+	if platform != lib.PlatformIos {
+		patterns = append(patterns, "build-*.apk")
+	}
+
+	var newestPath string
+
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+
+		for _, match := range matches {
+			if !lib.IsRegularFile(match) {
+				continue
+			}
+
+			if len(newestPath) == 0 || lib.GetModificationTimeUTC(match).After(lib.GetModificationTimeUTC(newestPath)) {
+				newestPath = match
+			}
+		}
+	}
+
+	if len(newestPath) == 0 {
+		return "", fmt.Errorf("No local EAS build artifact (%v) found in the current directory", strings.Join(patterns, " or "))
+	}
+
+	return newestPath, nil
+}
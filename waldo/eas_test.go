@@ -0,0 +1,181 @@
+package waldo
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func makeTestTarGz(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	for name, contents := range files {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		tw.Write([]byte(contents))
+	}
+
+	tw.Close()
+	gw.Close()
+
+	return buf.Bytes()
+}
+
+func TestUploadActionFetchesEASBuild(t *testing.T) {
+	artifact := makeTestTarGz(t, map[string]string{
+		"Sample.app/Info.plist": "<plist/>",
+		"Sample.app/Sample":     "binary"})
+
+	var server *httptest.Server
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/artifacts/build.tar.gz" {
+			w.Write(artifact)
+
+			return
+		}
+
+		build := map[string]any{
+			"id":            "bld-1",
+			"platform":      "IOS",
+			"status":        "FINISHED",
+			"distribution":  "SIMULATOR",
+			"buildProfile":  "preview",
+			"gitCommitHash": "0123456789abcdef0123456789abcdef01234567",
+			"artifacts":     map[string]any{"buildUrl": server.URL + "/artifacts/build.tar.gz"}}
+
+		json.NewEncoder(w).Encode(map[string]any{
+			"data": map[string]any{"builds": map[string]any{"byId": build}}})
+	}))
+
+	defer server.Close()
+
+	t.Setenv("WALDO_EAS_API_ENDPOINT_OVERRIDE", server.URL)
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	ua := NewUploadAction(&UploadOptions{FromEAS: "bld-1"}, ioStreams)
+
+	ua.config = &data.Configuration{}
+
+	defer ua.cleanup()
+
+	if err := ua.resolveEASBuild(); err != nil {
+		t.Fatalf("resolveEASBuild() error = %v", err)
+	}
+
+	//
+	// The variant name and git commit are known before the build is fetched
+	// (and the project policy checked):
+	//
+	if ua.options.VariantName != "preview" || ua.options.GitCommit != "0123456789abcdef0123456789abcdef01234567" {
+		t.Errorf("options after resolveEASBuild() = %+v", ua.options)
+	}
+
+	if err := ua.prepareBuild(); err != nil {
+		t.Fatalf("prepareBuild() error = %v", err)
+	}
+
+	if filepath.Base(ua.buildPath) != "Sample.app" || !lib.IsDirectory(ua.buildPath) {
+		t.Errorf("buildPath = %q, want unpacked Sample.app", ua.buildPath)
+	}
+}
+
+func TestUploadActionEASBuildWithoutArtifact(t *testing.T) {
+	tests := []struct {
+		platform  string
+		artifacts any
+	}{
+		{"ANDROID", nil},
+		{"IOS", map[string]any{"buildUrl": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.platform, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				build := map[string]any{
+					"id":           "bld-1",
+					"platform":     tt.platform,
+					"status":       "FINISHED",
+					"distribution": "SIMULATOR",
+					"artifacts":    tt.artifacts}
+
+				json.NewEncoder(w).Encode(map[string]any{
+					"data": map[string]any{"builds": map[string]any{"byId": build}}})
+			}))
+
+			defer server.Close()
+
+			t.Setenv("WALDO_EAS_API_ENDPOINT_OVERRIDE", server.URL)
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ua := NewUploadAction(&UploadOptions{FromEAS: "bld-1"}, ioStreams)
+
+			ua.config = &data.Configuration{}
+
+			if err := ua.resolveEASBuild(); err == nil || !strings.Contains(err.Error(), `EAS build "bld-1" has no downloadable artifact`) {
+				t.Errorf("resolveEASBuild() error = %v, want no downloadable artifact", err)
+			}
+		})
+	}
+}
+
+func TestFindLocalEASBuild(t *testing.T) {
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	if _, err := findLocalEASBuild(lib.PlatformUnknown); err == nil || !strings.Contains(err.Error(), "build-*.tar.gz or build-*.apk") {
+		t.Errorf("findLocalEASBuild() error = %v, want none found", err)
+	}
+
+	now := time.Now()
+
+	for name, age := range map[string]time.Duration{"build-1.apk": 2 * time.Hour, "build-2.tar.gz": time.Hour, "build-3.apk": 0, "other.apk": 0} {
+		if err := os.WriteFile(name, []byte("build"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		os.Chtimes(name, now.Add(-age), now.Add(-age))
+	}
+
+	tests := []struct {
+		platform lib.Platform
+		want     string
+	}{
+		{lib.PlatformUnknown, "build-3.apk"},
+		{lib.PlatformAndroid, "build-3.apk"},
+		{lib.PlatformIos, "build-2.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		if got, err := findLocalEASBuild(tt.platform); err != nil || got != tt.want {
+			t.Errorf("findLocalEASBuild(%v) = %q, %v, want %q", tt.platform, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package waldo
+
+import (
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+)
+
+func emitGitInfo(ioStreams *lib.IOStreams, gitInfo *git.Info) {
+	if gitInfo == nil {
+		ioStreams.Printf("  git:              (not a git repository)\n")
+
+		return
+	}
+
+	head := gitInfo.Branch
+
+	if gitInfo.Detached {
+		if len(head) > 0 {
+			head += " (detached HEAD)"
+		} else {
+			head = "(detached HEAD)"
+		}
+	}
+
+	ioStreams.Printf("  git head:         %v\n", head)
+	ioStreams.Printf("  git author:       %v <%v>\n", gitInfo.AuthorName, gitInfo.AuthorEmail)
+	ioStreams.Printf("  git message:      %q\n", firstLine(gitInfo.Message))
+	ioStreams.Printf("  git remote:       %v\n", gitInfo.RemoteURL)
+	ioStreams.Printf("  git dirty:        %v\n", gitInfo.Dirty)
+	ioStreams.Printf("  git shallow:      %v\n", gitInfo.Shallow)
+}
+
+func firstLine(text string) string {
+	for idx, ch := range text {
+		if ch == '\n' {
+			return text[:idx]
+		}
+	}
+
+	return text
+}
+
+func inferGitInfo(rev string) *git.Info {
+	gitInfo, err := git.Inspect(rev)
+
+	if err != nil {
+		return nil
+	}
+
+	return gitInfo
+}
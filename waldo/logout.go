@@ -0,0 +1,68 @@
+package waldo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+type LogoutOptions struct {
+	Profile string
+	Verbose bool
+}
+
+type LogoutAction struct {
+	ioStreams   *lib.IOStreams
+	options     *LogoutOptions
+	runtimeInfo *lib.RuntimeInfo
+}
+
+//-----------------------------------------------------------------------------
+
+func NewLogoutAction(options *LogoutOptions, ioStreams *lib.IOStreams) *LogoutAction {
+	runtimeInfo := lib.DetectRuntimeInfo()
+
+	return &LogoutAction{
+		ioStreams:   ioStreams,
+		options:     options,
+		runtimeInfo: runtimeInfo}
+}
+
+//-----------------------------------------------------------------------------
+
+func (la *LogoutAction) Perform() error {
+	profile, _, err := data.SetupProfile(data.CreateKindNever)
+
+	if errors.Is(err, data.ErrProfileNotFound) {
+		la.ioStreams.Printf("\nNo saved credentials to remove\n")
+
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	name := profile.ResolveName(la.options.Profile)
+
+	if profile.Find(name) == nil {
+		la.ioStreams.Printf("\nNo saved credentials to remove for profile %q\n", name)
+
+		return nil
+	}
+
+	if err := removeProfile(profile, name, la.ioStreams); err != nil {
+		return fmt.Errorf("Unable to log out, error: %v", err)
+	}
+
+	la.ioStreams.Printf("\nCredentials for profile %q removed\n", name)
+
+	if len(os.Getenv("WALDO_UPLOAD_TOKEN")) > 0 || len(os.Getenv("WALDO_UPLOAD_TOKEN_FILE")) > 0 {
+		la.ioStreams.Printf("\nNote: WALDO_UPLOAD_TOKEN (or WALDO_UPLOAD_TOKEN_FILE) is still set in the environment\n")
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package waldo
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	defaultReleaseNotesLimit = 20
+
+	defaultReleaseNotesTemplate = `{{range .Commits}}- {{.Subject}}
+{{end}}{{if .Truncated}}- …
+{{end}}`
+)
+
+//-----------------------------------------------------------------------------
+
+type releaseNotesCommit struct {
+	Author   string
+	SHA      string
+	ShortSHA string
+	Subject  string
+}
+
+type releaseNotesData struct {
+	Commits    []*releaseNotesCommit
+	FromCommit string
+	ToCommit   string
+	Truncated  bool
+}
+
+//-----------------------------------------------------------------------------
+
+func (ua *UploadAction) detectDescription() (string, error) {
+	switch notes := ua.options.ReleaseNotes; notes {
+	case "":
+		return "", nil
+
+	case "auto":
+		return ua.generateReleaseNotes()
+
+	default:
+		return notes, nil
+	}
+}
+
+func (ua *UploadAction) detectReleaseNotesLimit() int {
+	if limit := ua.options.ReleaseNotesLimit; limit > 0 {
+		return limit
+	}
+
+	if limit := ua.config.ReleaseNotes.Limit; limit > 0 {
+		return limit
+	}
+
+	return defaultReleaseNotesLimit
+}
+
+func (ua *UploadAction) detectReleaseNotesTemplate() string {
+	if tmpl := ua.options.ReleaseNotesTemplate; len(tmpl) > 0 {
+		return tmpl
+	}
+
+	if tmpl := ua.config.ReleaseNotes.Template; len(tmpl) > 0 {
+		return tmpl
+	}
+
+	return defaultReleaseNotesTemplate
+}
+
+// findPreviousCommit returns the git commit of the previous upload of this
+// app/variant. Waldo does not expose it, so it comes from the upload ledger,
+// which only records uploads made from this machine (a fresh CI runner has
+// none).
+func (ua *UploadAction) findPreviousCommit() string {
+	ldg, err := data.SetupLedger()
+
+	if err != nil {
+		return ""
+	}
+
+	entry := ldg.Find(data.MakeLedgerKey(ua.appID, ua.uploadToken, ua.options.VariantName))
+
+	if entry == nil {
+		return ""
+	}
+
+	return entry.GitCommit
+}
+
+func (ua *UploadAction) generateReleaseNotes() (string, error) {
+	toCommit := orHead(ua.options.GitCommit)
+
+	limit := ua.detectReleaseNotesLimit()
+
+	tmpl, err := template.New("release_notes").Parse(ua.detectReleaseNotesTemplate())
+
+	if err != nil {
+		return "", fmt.Errorf("Invalid release notes template, error: %v", err)
+	}
+
+	rnd := &releaseNotesData{
+		FromCommit: ua.findPreviousCommit(),
+		ToCommit:   toCommit}
+
+	if len(rnd.FromCommit) == 0 {
+		ua.ioStreams.Printf("\nNo previous upload of this app/variant in the local upload ledger -- release notes list the latest %d commits\n", limit)
+	}
+
+	rnd.Commits, err = collectCommits(rnd.FromCommit, toCommit, limit+1, ua.targetSourcePaths()...)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(rnd.Commits) > limit {
+		rnd.Commits = rnd.Commits[:limit]
+		rnd.Truncated = true
+	}
+
+	var sb strings.Builder
+
+	if err := tmpl.Execute(&sb, rnd); err != nil {
+		return "", fmt.Errorf("Unable to generate release notes, error: %v", err)
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+func (ua *UploadAction) recordUpload() {
+	ldg, err := data.SetupLedger()
+
+	if err != nil {
+		return
+	}
+
+	commit, _ := git.ResolveCommit(orHead(ua.options.GitCommit))
+
+	ldg.Record(
+		data.MakeLedgerKey(ua.appID, ua.uploadToken, ua.options.VariantName),
+		&data.LedgerEntry{
+			BuildVersion: ua.buildVersion,
+			GitCommit:    commit,
+			UploadedAt:   time.Now().UTC()})
+
+	if err := ldg.Save(); err != nil && ua.options.Verbose {
+		ua.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Unable to update upload ledger, error: %v", err))
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+func collectCommits(fromCommit, toCommit string, maxCount int, paths ...string) ([]*releaseNotesCommit, error) {
+	args := []string{"log", "--no-merges", "--format=%H%x1f%an%x1f%s", fmt.Sprintf("--max-count=%d", maxCount)}
+
+	//
+	// If the previous commit is unknown or unreachable (for example, in a
+	// shallow clone), fall back to the most recent commits:
+	//
+	if _, err := git.ResolveCommit(fromCommit); len(fromCommit) > 0 && err == nil {
+		args = append(args, fromCommit+".."+toCommit)
+	} else {
+		args = append(args, toCommit)
+	}
+
+	if len(paths) > 0 {
+		args = append(append(args, "--"), paths...)
+	}
+
+	output, err := git.Run(args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("Unable to collect release notes, error: %v", err)
+	}
+
+	var commits []*releaseNotesCommit
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x1f", 3)
+
+		if len(fields) != 3 {
+			continue
+		}
+
+		commits = append(commits, &releaseNotesCommit{
+			Author:   fields[1],
+			SHA:      fields[0],
+			ShortSHA: fields[0][:min(len(fields[0]), 7)],
+			Subject:  fields[2]})
+	}
+
+	return commits, nil
+}
+
+func orHead(commit string) string {
+	if len(commit) > 0 {
+		return commit
+	}
+
+	return "HEAD"
+}
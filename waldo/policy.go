@@ -0,0 +1,235 @@
+package waldo
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+	"github.com/waldoapp/waldo-go-cli/waldo/artifact"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	policyOverrideMetadataKey = "policy.override_reason"
+)
+
+//-----------------------------------------------------------------------------
+
+func (ta *TriggerAction) checkPolicy() error {
+	violations := checkGitPolicy(&ta.config.Policy, ta.options.GitCommit, ta.branch, ta.gitInfo)
+
+	if err := enforcePolicy(ta.ioStreams, violations, ta.options.OverridePolicy); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		ta.overrideReason = ta.options.OverridePolicy
+	}
+
+	return nil
+}
+
+// checkPolicy checks the project policy, reporting all violations together. A
+// local build has already been prepared, so this includes the checks that
+// depend on the build itself. A build from a URL or from EAS is only fetched
+// once this passes (so as not to download it in vain), and those checks are
+// deferred to checkBuildPolicy.
+func (ua *UploadAction) checkPolicy() error {
+	policy := &ua.config.Policy
+
+	violations := checkGitPolicy(policy, ua.options.GitCommit, ua.options.GitBranch, ua.gitInfo)
+
+	if policy.RequireVariantName && len(ua.options.VariantName) == 0 {
+		violations = append(violations, "A variant name is required")
+	}
+
+	if !ua.fetchesBuild() {
+		violations = append(violations, ua.checkBuildVersionPolicy()...)
+	}
+
+	if err := enforcePolicy(ua.ioStreams, violations, ua.options.OverridePolicy); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		ua.metadata[policyOverrideMetadataKey] = ua.options.OverridePolicy
+	}
+
+	return nil
+}
+
+// checkBuildPolicy checks the parts of the project policy that depend on the
+// build itself, once a build from a URL or from EAS has been fetched and
+// unpacked.
+func (ua *UploadAction) checkBuildPolicy() error {
+	violations := ua.checkBuildVersionPolicy()
+
+	if err := enforcePolicy(ua.ioStreams, violations, ua.options.OverridePolicy); err != nil {
+		return err
+	}
+
+	if len(violations) > 0 {
+		ua.metadata[policyOverrideMetadataKey] = ua.options.OverridePolicy
+	}
+
+	return nil
+}
+
+func (ua *UploadAction) checkBuildVersionPolicy() []string {
+	minIncrement := ua.config.Policy.MinBuildVersionIncrement
+
+	if minIncrement <= 0 {
+		return nil
+	}
+
+	if violation := ua.checkBuildVersionIncrement(minIncrement); len(violation) > 0 {
+		return []string{violation}
+	}
+
+	return nil
+}
+
+// checkBuildVersionIncrement compares the build version with that of the
+// previous upload of this app/variant. Waldo does not expose the latter, so it
+// comes from the upload ledger, which only records uploads made from this
+// machine; without a previous upload (as on a fresh CI runner), the check
+// passes.
+func (ua *UploadAction) checkBuildVersionIncrement(minIncrement int) string {
+	info, err := artifact.Inspect(ua.buildPath)
+
+	if err != nil || len(info.BuildVersion) == 0 {
+		return "Unable to determine build version to verify increment"
+	}
+
+	ua.buildVersion = info.BuildVersion
+
+	ldg, err := data.SetupLedger()
+
+	if err != nil {
+		return ""
+	}
+
+	entry := ldg.Find(data.MakeLedgerKey(ua.appID, ua.uploadToken, ua.options.VariantName))
+
+	if entry == nil || len(entry.BuildVersion) == 0 {
+		return ""
+	}
+
+	increment, ok := compareBuildVersions(entry.BuildVersion, ua.buildVersion)
+
+	if !ok {
+		return fmt.Sprintf("Unable to compare build version %q with previously uploaded build version %q", ua.buildVersion, entry.BuildVersion)
+	}
+
+	if increment < minIncrement {
+		return fmt.Sprintf("Build version %q must be at least %d greater than previously uploaded build version %q", ua.buildVersion, minIncrement, entry.BuildVersion)
+	}
+
+	return ""
+}
+
+//-----------------------------------------------------------------------------
+
+func checkGitPolicy(policy *data.PolicyConfig, commit, branch string, gitInfo *git.Info) []string {
+	var violations []string
+
+	if policy.RequireGitCommit && len(commit) == 0 {
+		violations = append(violations, "A git commit is required")
+	}
+
+	if policy.RejectDirty && gitInfo != nil && gitInfo.Dirty {
+		violations = append(violations, "The git working tree has uncommitted changes")
+	}
+
+	if len(policy.AllowedBranches) > 0 {
+		switch {
+		case len(branch) == 0:
+			violations = append(violations, fmt.Sprintf("A git branch is required (allowed: %v)", strings.Join(policy.AllowedBranches, ", ")))
+
+		case !matchesAnyBranch(policy.AllowedBranches, branch):
+			violations = append(violations, fmt.Sprintf("Git branch %q is not allowed (allowed: %v)", branch, strings.Join(policy.AllowedBranches, ", ")))
+		}
+	}
+
+	return violations
+}
+
+// compareBuildVersions returns the increment from the old build version to
+// the new one, measured at the first dot-separated component that differs
+// (for example, "41" to "43" is 2, and "1.2.9" to "1.3.0" is 1).
+func compareBuildVersions(oldVersion, newVersion string) (int, bool) {
+	oldParts, ok := parseBuildVersion(oldVersion)
+
+	if !ok {
+		return 0, false
+	}
+
+	newParts, ok := parseBuildVersion(newVersion)
+
+	if !ok {
+		return 0, false
+	}
+
+	for idx := 0; idx < max(len(oldParts), len(newParts)); idx++ {
+		var oldPart, newPart int
+
+		if idx < len(oldParts) {
+			oldPart = oldParts[idx]
+		}
+
+		if idx < len(newParts) {
+			newPart = newParts[idx]
+		}
+
+		if oldPart != newPart {
+			return newPart - oldPart, true
+		}
+	}
+
+	return 0, true
+}
+
+func enforcePolicy(ioStreams *lib.IOStreams, violations []string, overrideReason string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	list := "\n  - " + strings.Join(violations, "\n  - ")
+
+	if len(overrideReason) == 0 {
+		return fmt.Errorf("Project policy violated:%v\nUse --override_policy <reason> to proceed anyway", list)
+	}
+
+	ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Project policy overridden (reason: %q):%v", overrideReason, list))
+
+	return nil
+}
+
+func matchesAnyBranch(patterns []string, branch string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, branch); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseBuildVersion(version string) ([]int, bool) {
+	var parts []int
+
+	for _, field := range strings.Split(strings.TrimSpace(version), ".") {
+		part, err := strconv.Atoi(field)
+
+		if err != nil || part < 0 {
+			return nil, false
+		}
+
+		parts = append(parts, part)
+	}
+
+	return parts, true
+}
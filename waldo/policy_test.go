@@ -0,0 +1,205 @@
+package waldo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const testInfoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleExecutable</key>
+	<string>Sample</string>
+	<key>CFBundleIdentifier</key>
+	<string>com.example.sample</string>
+	<key>CFBundleVersion</key>
+	<string>42</string>
+</dict>
+</plist>
+`
+
+func TestUploadActionBuildVersionPolicy(t *testing.T) {
+	const ciToken = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		name            string
+		previousVersion string
+		minIncrement    int
+		overridePolicy  string
+		wantErr         string
+		wantOverride    bool
+	}{
+		{name: "no previous upload", minIncrement: 1},
+		{name: "enough increment", previousVersion: "41", minIncrement: 1},
+		{name: "not enough increment", previousVersion: "41", minIncrement: 2, wantErr: `must be at least 2 greater than previously uploaded build version "41"`},
+		{name: "overridden", previousVersion: "42", minIncrement: 1, overridePolicy: "hotfix", wantOverride: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			if len(tt.previousVersion) > 0 {
+				ldg, err := data.SetupLedger()
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ldg.Record(data.MakeLedgerKey("", ciToken, ""), &data.LedgerEntry{BuildVersion: tt.previousVersion, UploadedAt: time.Now()})
+
+				if err := ldg.Save(); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			//
+			// The build version of an archived app is only known once it has
+			// been unpacked:
+			//
+			archivePath := filepath.Join(t.TempDir(), "Sample.tar.gz")
+
+			if err := os.WriteFile(archivePath, makeTestTarGz(t, map[string]string{
+				"Sample.app/Info.plist": testInfoPlist,
+				"Sample.app/Sample":     "binary"}), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ua := NewUploadAction(&UploadOptions{OverridePolicy: tt.overridePolicy}, ioStreams)
+
+			ua.buildPath = archivePath
+			ua.config = &data.Configuration{Policy: data.PolicyConfig{MinBuildVersionIncrement: tt.minIncrement}}
+			ua.metadata = make(map[string]string)
+			ua.uploadToken = ciToken
+
+			defer ua.cleanup()
+
+			if err := ua.prepareBuild(); err != nil {
+				t.Fatalf("prepareBuild() error = %v", err)
+			}
+
+			err := ua.checkPolicy()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("checkPolicy() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("checkPolicy() error = %v", err)
+			}
+
+			if ua.buildVersion != "42" {
+				t.Errorf("buildVersion = %q, want %q", ua.buildVersion, "42")
+			}
+
+			if _, found := ua.metadata[policyOverrideMetadataKey]; found != tt.wantOverride {
+				t.Errorf("metadata = %v, want override %v", ua.metadata, tt.wantOverride)
+			}
+		})
+	}
+}
+
+func TestUploadActionPolicyViolations(t *testing.T) {
+	const ciToken = "0123456789abcdef0123456789abcdef"
+
+	const (
+		variantViolation = "A variant name is required"
+		versionViolation = `Build version "42" must be at least 1 greater than previously uploaded build version "42"`
+	)
+
+	tests := []struct {
+		name           string
+		fromURL        string
+		wantViolations []string
+	}{
+		{
+			name:           "local build",
+			wantViolations: []string{variantViolation, versionViolation}},
+		{
+			name:           "build from URL",
+			fromURL:        "https://example.com/Sample.tar.gz",
+			wantViolations: []string{variantViolation}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+
+			ldg, err := data.SetupLedger()
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ldg.Record(data.MakeLedgerKey("", ciToken, ""), &data.LedgerEntry{BuildVersion: "42", UploadedAt: time.Now()})
+
+			if err := ldg.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			archivePath := filepath.Join(t.TempDir(), "Sample.tar.gz")
+
+			if err := os.WriteFile(archivePath, makeTestTarGz(t, map[string]string{
+				"Sample.app/Info.plist": testInfoPlist,
+				"Sample.app/Sample":     "binary"}), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ua := NewUploadAction(&UploadOptions{FromURL: tt.fromURL}, ioStreams)
+
+			ua.buildPath = archivePath
+			ua.config = &data.Configuration{Policy: data.PolicyConfig{MinBuildVersionIncrement: 1, RequireVariantName: true}}
+			ua.metadata = make(map[string]string)
+			ua.uploadToken = ciToken
+
+			defer ua.cleanup()
+
+			//
+			// Perform prepares a local build before checking the policy, but
+			// fetches a build from a URL only after:
+			//
+			if len(tt.fromURL) == 0 {
+				if err := ua.prepareBuild(); err != nil {
+					t.Fatalf("prepareBuild() error = %v", err)
+				}
+			}
+
+			err = ua.checkPolicy()
+
+			if err == nil {
+				t.Fatal("checkPolicy() error = nil")
+			}
+
+			if want := "Project policy violated:\n  - " + strings.Join(tt.wantViolations, "\n  - ") + "\n"; !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("checkPolicy() error = %q, want %q", err, want)
+			}
+
+			if len(tt.fromURL) == 0 {
+				return
+			}
+
+			if err := ua.unpackBuild(archivePath); err != nil {
+				t.Fatalf("unpackBuild() error = %v", err)
+			}
+
+			if err := ua.checkBuildPolicy(); err == nil || !strings.Contains(err.Error(), versionViolation) {
+				t.Errorf("checkBuildPolicy() error = %v, want %q", err, versionViolation)
+			}
+		})
+	}
+}
@@ -0,0 +1,263 @@
+package waldo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+type ProfileOptions struct {
+	Name string
+}
+
+type ProfileDeleteAction struct {
+	ioStreams *lib.IOStreams
+	options   *ProfileOptions
+}
+
+type ProfileListAction struct {
+	ioStreams *lib.IOStreams
+	options   *ProfileOptions
+}
+
+type ProfileShowAction struct {
+	ioStreams *lib.IOStreams
+	options   *ProfileOptions
+}
+
+type ProfileUseAction struct {
+	ioStreams *lib.IOStreams
+	options   *ProfileOptions
+}
+
+//-----------------------------------------------------------------------------
+
+func NewProfileDeleteAction(options *ProfileOptions, ioStreams *lib.IOStreams) *ProfileDeleteAction {
+	return &ProfileDeleteAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+func NewProfileListAction(options *ProfileOptions, ioStreams *lib.IOStreams) *ProfileListAction {
+	return &ProfileListAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+func NewProfileShowAction(options *ProfileOptions, ioStreams *lib.IOStreams) *ProfileShowAction {
+	return &ProfileShowAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+func NewProfileUseAction(options *ProfileOptions, ioStreams *lib.IOStreams) *ProfileUseAction {
+	return &ProfileUseAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+//-----------------------------------------------------------------------------
+
+func (pda *ProfileDeleteAction) Perform() error {
+	profile, _, err := data.SetupProfile(data.CreateKindNever)
+
+	if err != nil {
+		return err
+	}
+
+	name := pda.options.Name
+
+	if profile.Find(name) == nil {
+		return fmt.Errorf("Waldo profile not found: %q", name)
+	}
+
+	if err := removeProfile(profile, name, pda.ioStreams); err != nil {
+		return fmt.Errorf("Unable to delete profile, error: %v", err)
+	}
+
+	pda.ioStreams.Printf("\nProfile %q deleted from %q\n", name, profile.Path())
+
+	return nil
+}
+
+func (pla *ProfileListAction) Perform() error {
+	profile, _, err := data.SetupProfile(data.CreateKindNever)
+
+	if err != nil && !errors.Is(err, data.ErrProfileNotFound) {
+		return err
+	}
+
+	if err != nil || len(profile.Profiles) == 0 {
+		pla.ioStreams.Printf("\nNo profiles found -- use “waldo auth” to create one\n")
+
+		return nil
+	}
+
+	current := profile.ResolveName("")
+
+	pla.ioStreams.Printf("\n")
+
+	for _, name := range lib.SortedKeys(profile.Profiles) {
+		if name == current {
+			pla.ioStreams.Printf("* %v\n", name)
+		} else {
+			pla.ioStreams.Printf("  %v\n", name)
+		}
+	}
+
+	return nil
+}
+
+func (psa *ProfileShowAction) Perform() error {
+	profile, _, err := data.SetupProfile(data.CreateKindNever)
+
+	if err != nil {
+		return err
+	}
+
+	name := profile.ResolveName(psa.options.Name)
+
+	if profile.Find(name) == nil {
+		return fmt.Errorf("Waldo profile not found: %q", name)
+	}
+
+	store, err := openCredentialStore(profile, psa.ioStreams)
+
+	if err != nil {
+		return err
+	}
+
+	token, err := store.Get(name)
+
+	if err != nil {
+		return err
+	}
+
+	psa.ioStreams.Printf("\n")
+	psa.ioStreams.Printf("  profile:          %v\n", name)
+	psa.ioStreams.Printf("  current:          %v\n", name == profile.ResolveName(""))
+	psa.ioStreams.Printf("  token:            %v\n", maskToken(token))
+	psa.ioStreams.Printf("  path:             %v\n", profile.Path())
+	psa.ioStreams.Printf("  credentials:      %v\n", store.Describe())
+	psa.ioStreams.Printf("  encrypted:        %v\n", store.IsEncrypted())
+
+	return nil
+}
+
+func (pua *ProfileUseAction) Perform() error {
+	name := pua.options.Name
+
+	errNotFound := fmt.Errorf("Waldo profile not found: %q", name)
+
+	_, err := data.UpdateProfile(data.CreateKindNever, func(prf *data.Profile) error {
+		if prf.Find(name) == nil {
+			return errNotFound
+		}
+
+		prf.CurrentProfile = name
+
+		prf.MarkDirty()
+
+		return nil
+	})
+
+	if err == errNotFound || errors.Is(err, data.ErrProfileNotFound) {
+		return err
+	}
+
+	if err != nil {
+		return fmt.Errorf("Unable to switch profile, error: %v", err)
+	}
+
+	pua.ioStreams.Printf("\nSwitched to profile %q\n", name)
+
+	if envName := os.Getenv("WALDO_PROFILE"); len(envName) > 0 && envName != name {
+		pua.ioStreams.Printf("\nNote: WALDO_PROFILE is set to %q in the environment and takes precedence\n", envName)
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// findAPIToken returns the API token to use absent an explicit --upload_token
+// option, along with a description of where it came from. An explicitly named
+// profile takes precedence over WALDO_UPLOAD_TOKEN, which in turn takes
+// precedence over the profile selected by WALDO_PROFILE or “waldo profile
+// use” (or the default profile).
+func findAPIToken(profileName string, ioStreams *lib.IOStreams) (string, string, error) {
+	explicit := len(profileName) > 0
+
+	if !explicit {
+		token, err := lib.GetenvSecret("WALDO_UPLOAD_TOKEN")
+
+		if err != nil {
+			return "", "", err
+		}
+
+		if len(token) > 0 {
+			if len(os.Getenv("WALDO_UPLOAD_TOKEN")) > 0 {
+				return token, "WALDO_UPLOAD_TOKEN environment variable", nil
+			}
+
+			return token, fmt.Sprintf("file %q (WALDO_UPLOAD_TOKEN_FILE)", os.Getenv("WALDO_UPLOAD_TOKEN_FILE")), nil
+		}
+	}
+
+	profile, _, err := data.SetupProfile(data.CreateKindNever)
+
+	if err != nil {
+		if explicit || !errors.Is(err, data.ErrProfileNotFound) {
+			return "", "", err
+		}
+
+		return "", "", nil
+	}
+
+	name := profile.ResolveName(profileName)
+
+	if profile.Find(name) == nil {
+		if explicit {
+			return "", "", fmt.Errorf("Waldo profile not found: %q", name)
+		}
+
+		return "", "", nil
+	}
+
+	store, err := openCredentialStore(profile, ioStreams)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	token, err := store.Get(name)
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return token, fmt.Sprintf("profile %q in %v", name, store.Describe()), nil
+}
+
+func removeProfile(profile *data.Profile, name string, ioStreams *lib.IOStreams) error {
+	store, err := openCredentialStore(profile, ioStreams)
+
+	if err != nil {
+		return err
+	}
+
+	if err := store.Erase(name); err != nil {
+		return err
+	}
+
+	_, err = data.UpdateProfile(data.CreateKindNever, func(prf *data.Profile) error {
+		prf.Remove(name)
+
+		return nil
+	})
+
+	return err
+}
@@ -0,0 +1,296 @@
+package waldo
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/ci"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+const (
+	reportCommentMarker = "<!-- waldo-cli-report -->"
+)
+
+//-----------------------------------------------------------------------------
+
+type report struct {
+	commit     string
+	detailsURL string
+	kind       string // "upload" or "trigger"
+	summary    string // Markdown
+	title      string
+}
+
+type reporter interface {
+	Report(rpt *report) error
+}
+
+//-----------------------------------------------------------------------------
+
+type gitHubReporter struct {
+	client      *api.GitHubClient
+	ioStreams   *lib.IOStreams
+	pullRequest string
+}
+
+type gitLabReporter struct {
+	client       *api.GitLabClient
+	ioStreams    *lib.IOStreams
+	mergeRequest string
+}
+
+//-----------------------------------------------------------------------------
+
+func newReporter(name string, ciInfo *ci.Info, gitInfo *git.Info, verbose bool, ioStreams *lib.IOStreams) (reporter, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+
+	case "github":
+		return newGitHubReporter(ciInfo, gitInfo, verbose, ioStreams)
+
+	case "gitlab":
+		return newGitLabReporter(ciInfo, gitInfo, verbose, ioStreams)
+
+	default:
+		return nil, fmt.Errorf("Invalid report destination: %q", name)
+	}
+}
+
+func newGitHubReporter(ciInfo *ci.Info, gitInfo *git.Info, verbose bool, ioStreams *lib.IOStreams) (reporter, error) {
+	token := os.Getenv("WALDO_GITHUB_TOKEN")
+
+	if len(token) == 0 {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+
+	if len(token) == 0 {
+		return nil, fmt.Errorf("Unable to report to GitHub, error: neither WALDO_GITHUB_TOKEN nor GITHUB_TOKEN is set")
+	}
+
+	repository := os.Getenv("GITHUB_REPOSITORY")
+
+	if len(repository) == 0 && gitInfo != nil {
+		repository = parseRepositoryPath(gitInfo.RemoteURL)
+	}
+
+	if len(repository) == 0 {
+		return nil, fmt.Errorf("Unable to report to GitHub, error: unable to determine repository (set GITHUB_REPOSITORY)")
+	}
+
+	var pullRequest string
+
+	if ciInfo != nil && ciInfo.Provider == ci.ProviderGitHubActions {
+		pullRequest = ciInfo.PullRequest
+	}
+
+	return &gitHubReporter{
+		client:      api.NewGitHubClient(repository, token, verbose, ioStreams),
+		ioStreams:   ioStreams,
+		pullRequest: pullRequest}, nil
+}
+
+func newGitLabReporter(ciInfo *ci.Info, gitInfo *git.Info, verbose bool, ioStreams *lib.IOStreams) (reporter, error) {
+	token := os.Getenv("WALDO_GITLAB_TOKEN")
+
+	if len(token) == 0 {
+		token = os.Getenv("GITLAB_TOKEN")
+	}
+
+	if len(token) == 0 {
+		return nil, fmt.Errorf("Unable to report to GitLab, error: neither WALDO_GITLAB_TOKEN nor GITLAB_TOKEN is set")
+	}
+
+	projectID := os.Getenv("CI_PROJECT_ID")
+
+	if len(projectID) == 0 && gitInfo != nil {
+		projectID = parseRepositoryPath(gitInfo.RemoteURL)
+	}
+
+	if len(projectID) == 0 {
+		return nil, fmt.Errorf("Unable to report to GitLab, error: unable to determine project (set CI_PROJECT_ID)")
+	}
+
+	var mergeRequest string
+
+	if ciInfo != nil && ciInfo.Provider == ci.ProviderGitLabCI {
+		mergeRequest = ciInfo.PullRequest
+	}
+
+	return &gitLabReporter{
+		client:       api.NewGitLabClient(projectID, token, verbose, ioStreams),
+		ioStreams:    ioStreams,
+		mergeRequest: mergeRequest}, nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (ghr *gitHubReporter) Report(rpt *report) error {
+	if len(rpt.commit) > 0 {
+		if err := ghr.reportCommit(rpt); err != nil {
+			return err
+		}
+	}
+
+	if len(ghr.pullRequest) > 0 {
+		comment, err := ghr.client.FindComment(ghr.pullRequest, reportCommentMarker)
+
+		if err != nil {
+			return fmt.Errorf("Unable to report to GitHub, error: %v", err)
+		}
+
+		if comment != nil {
+			err = ghr.client.UpdateComment(comment.CommentID, upsertReportSection(comment.Body, rpt))
+		} else {
+			err = ghr.client.CreateComment(ghr.pullRequest, upsertReportSection("", rpt))
+		}
+
+		if err != nil {
+			return fmt.Errorf("Unable to report to GitHub, error: %v", err)
+		}
+	}
+
+	ghr.ioStreams.Printf("\nReported %v result to GitHub\n", rpt.kind)
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (ghr *gitHubReporter) reportCommit(rpt *report) error {
+	checkRun := &api.GitHubCheckRun{
+		Conclusion: "success",
+		DetailsURL: rpt.detailsURL,
+		HeadSHA:    rpt.commit,
+		Name:       "Waldo " + rpt.kind,
+		Output: api.GitHubCheckRunOutput{
+			Summary: rpt.summary,
+			Title:   rpt.title},
+		Status: "completed"}
+
+	status, err := ghr.client.CreateCheckRun(checkRun)
+
+	if err == nil {
+		return nil
+	}
+
+	//
+	// Check runs can only be created with a GitHub App token (such as the
+	// GITHUB_TOKEN provided by GitHub Actions); fall back to a commit status:
+	//
+	if status != http.StatusForbidden && status != http.StatusNotFound && status != http.StatusUnprocessableEntity {
+		return fmt.Errorf("Unable to report to GitHub, error: %v", err)
+	}
+
+	commitStatus := &api.GitHubCommitStatus{
+		Context:     "waldo/" + rpt.kind,
+		Description: rpt.title,
+		State:       "success",
+		TargetURL:   rpt.detailsURL}
+
+	if err := ghr.client.CreateCommitStatus(rpt.commit, commitStatus); err != nil {
+		return fmt.Errorf("Unable to report to GitHub, error: %v", err)
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (glr *gitLabReporter) Report(rpt *report) error {
+	if len(rpt.commit) > 0 {
+		status := &api.GitLabCommitStatus{
+			Description: rpt.title,
+			Name:        "waldo/" + rpt.kind,
+			State:       "success",
+			TargetURL:   rpt.detailsURL}
+
+		if err := glr.client.CreateCommitStatus(rpt.commit, status); err != nil {
+			return fmt.Errorf("Unable to report to GitLab, error: %v", err)
+		}
+	}
+
+	if len(glr.mergeRequest) > 0 {
+		note, err := glr.client.FindNote(glr.mergeRequest, reportCommentMarker)
+
+		if err != nil {
+			return fmt.Errorf("Unable to report to GitLab, error: %v", err)
+		}
+
+		if note != nil {
+			err = glr.client.UpdateNote(glr.mergeRequest, note.NoteID, upsertReportSection(note.Body, rpt))
+		} else {
+			err = glr.client.CreateNote(glr.mergeRequest, upsertReportSection("", rpt))
+		}
+
+		if err != nil {
+			return fmt.Errorf("Unable to report to GitLab, error: %v", err)
+		}
+	}
+
+	glr.ioStreams.Printf("\nReported %v result to GitLab\n", rpt.kind)
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func emitReportError(ioStreams *lib.IOStreams, err error) {
+	//
+	// The upload or trigger itself succeeded; a reporting failure should not
+	// fail the command:
+	//
+	ioStreams.EmitError(data.CLIPrefix, err)
+}
+
+// formatReportLink formats whatever is known of the ID and URL of the build
+// or run for a report summary.
+func formatReportLink(noun, id, url string) string {
+	switch {
+	case len(id) > 0 && len(url) > 0:
+		return fmt.Sprintf("%v [%v](%v)", noun, id, url)
+
+	case len(url) > 0:
+		return fmt.Sprintf("[%v](%v)", noun, url)
+
+	default:
+		return fmt.Sprintf("%v `%v`", noun, id)
+	}
+}
+
+var repositoryPathRE = regexp.MustCompile(`[:/]([^/:]+/[^/]+?)(?:\.git)?/?$`)
+
+func parseRepositoryPath(remoteURL string) string {
+	if match := repositoryPathRE.FindStringSubmatch(remoteURL); match != nil {
+		return match[1]
+	}
+
+	return ""
+}
+
+// upsertReportSection replaces (or appends) the section of the given report
+// kind within the report comment body, leaving any other sections intact.
+func upsertReportSection(body string, rpt *report) string {
+	begin := fmt.Sprintf("<!-- waldo:%v -->", rpt.kind)
+	end := fmt.Sprintf("<!-- /waldo:%v -->", rpt.kind)
+	section := begin + "\n" + rpt.summary + "\n" + end
+
+	if !strings.Contains(body, reportCommentMarker) {
+		body = reportCommentMarker + "\n### Waldo\n"
+	}
+
+	if bidx := strings.Index(body, begin); bidx >= 0 {
+		if eidx := strings.Index(body[bidx:], end); eidx >= 0 {
+			return body[:bidx] + section + body[bidx+eidx+len(end):]
+		}
+	}
+
+	return strings.TrimRight(body, "\n") + "\n\n" + section + "\n"
+}
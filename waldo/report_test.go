@@ -0,0 +1,294 @@
+package waldo
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+)
+
+// codeHostStandIn stands in for the GitHub or GitLab API, answering each
+// request from the given routes (keyed by method and escaped path) and
+// recording the body of each request.
+type codeHostStandIn struct {
+	mutex    sync.Mutex
+	requests map[string]string
+	routes   map[string]func(w http.ResponseWriter)
+}
+
+func newCodeHostStandIn(t *testing.T, envName string, routes map[string]func(w http.ResponseWriter)) *codeHostStandIn {
+	si := &codeHostStandIn{
+		requests: make(map[string]string),
+		routes:   routes}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		si.mutex.Lock()
+		defer si.mutex.Unlock()
+
+		key := r.Method + " " + r.URL.EscapedPath()
+		body, _ := io.ReadAll(r.Body)
+
+		si.requests[key] = string(body)
+
+		if route, found := si.routes[key]; found {
+			route(w)
+		} else {
+			t.Errorf("unexpected request %v", key)
+
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+	t.Setenv(envName, server.URL)
+
+	return si
+}
+
+func (si *codeHostStandIn) body(t *testing.T, key string) map[string]any {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	raw, found := si.requests[key]
+
+	if !found {
+		t.Fatalf("no request %v", key)
+	}
+
+	var body map[string]any
+
+	if err := json.Unmarshal([]byte(raw), &body); err != nil {
+		t.Fatalf("request %v body = %q: %v", key, raw, err)
+	}
+
+	return body
+}
+
+func respond(status int, body string) func(w http.ResponseWriter) {
+	return func(w http.ResponseWriter) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}
+}
+
+func makeTestReport() *report {
+	return &report{
+		commit:     "0123456789abcdef0123456789abcdef01234567",
+		detailsURL: "https://app.waldo.com/applications/app-1/builds/bld-1",
+		kind:       "upload",
+		summary:    "**Build uploaded to Waldo** — build [bld-1](https://app.waldo.com/applications/app-1/builds/bld-1)",
+		title:      "Build uploaded to Waldo"}
+}
+
+func TestGitHubReporterReport(t *testing.T) {
+	const (
+		checkRuns = "POST /repos/acme/app/check-runs"
+		comments  = "GET /repos/acme/app/issues/12/comments"
+		create    = "POST /repos/acme/app/issues/12/comments"
+		statuses  = "POST /repos/acme/app/statuses/0123456789abcdef0123456789abcdef01234567"
+		update    = "PATCH /repos/acme/app/issues/comments/5"
+	)
+
+	existing := `[{"id": 4, "body": "LGTM"}, {"id": 5, "body": "` + reportCommentMarker + `\n### Waldo\n\n<!-- waldo:trigger -->\nrun\n<!-- /waldo:trigger -->\n"}]`
+
+	tests := []struct {
+		name       string
+		routes     map[string]func(w http.ResponseWriter)
+		wantErr    string
+		wantStatus bool
+		wantCreate bool
+		wantUpdate bool
+	}{
+		{
+			name: "check run and new comment",
+			routes: map[string]func(w http.ResponseWriter){
+				checkRuns: respond(http.StatusCreated, `{}`),
+				comments:  respond(http.StatusOK, `[]`),
+				create:    respond(http.StatusCreated, `{}`)},
+			wantCreate: true},
+		{
+			name: "commit status fallback and updated comment",
+			routes: map[string]func(w http.ResponseWriter){
+				checkRuns: respond(http.StatusForbidden, `{"message": "Resource not accessible by integration"}`),
+				statuses:  respond(http.StatusCreated, `{}`),
+				comments:  respond(http.StatusOK, existing),
+				update:    respond(http.StatusOK, `{}`)},
+			wantStatus: true,
+			wantUpdate: true},
+		{
+			name: "check run failure",
+			routes: map[string]func(w http.ResponseWriter){
+				checkRuns: respond(http.StatusInternalServerError, `{}`)},
+			wantErr: "500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			si := newCodeHostStandIn(t, "WALDO_GITHUB_API_ENDPOINT_OVERRIDE", tt.routes)
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ghr := &gitHubReporter{
+				client:      api.NewGitHubClient("acme/app", "gh-token", false, ioStreams),
+				ioStreams:   ioStreams,
+				pullRequest: "12"}
+
+			err := ghr.Report(makeTestReport())
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Report() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+
+			if checkRun := si.body(t, checkRuns); checkRun["details_url"] != makeTestReport().detailsURL || checkRun["conclusion"] != "success" {
+				t.Errorf("check run = %v", checkRun)
+			}
+
+			if tt.wantStatus {
+				if status := si.body(t, statuses); status["target_url"] != makeTestReport().detailsURL || status["context"] != "waldo/upload" {
+					t.Errorf("commit status = %v", status)
+				}
+			}
+
+			if tt.wantCreate {
+				if body, _ := si.body(t, create)["body"].(string); !strings.HasPrefix(body, reportCommentMarker) || !strings.Contains(body, "<!-- waldo:upload -->") {
+					t.Errorf("created comment = %q", body)
+				}
+			}
+
+			if tt.wantUpdate {
+				body, _ := si.body(t, update)["body"].(string)
+
+				if !strings.Contains(body, "<!-- waldo:trigger -->\nrun\n") || !strings.Contains(body, "bld-1") {
+					t.Errorf("updated comment = %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestGitLabReporterReport(t *testing.T) {
+	existing := `[{"id": 8, "body": "Looks good"}, {"id": 9, "body": "` + reportCommentMarker + `\n### Waldo\n"}]`
+
+	tests := []struct {
+		name       string
+		projectID  string
+		projectKey string // as escaped in the request path
+		notes      string
+		wantCreate bool
+	}{
+		{"namespace/path project and new note", "acme/mobile/app", "acme%2Fmobile%2Fapp", `[]`, true},
+		{"numeric project and updated note", "1234", "1234", existing, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := "/projects/" + tt.projectKey
+			status := "POST " + project + "/statuses/0123456789abcdef0123456789abcdef01234567"
+			notes := "GET " + project + "/merge_requests/7/notes"
+			create := "POST " + project + "/merge_requests/7/notes"
+			update := "PUT " + project + "/merge_requests/7/notes/9"
+
+			si := newCodeHostStandIn(t, "WALDO_GITLAB_API_ENDPOINT_OVERRIDE", map[string]func(w http.ResponseWriter){
+				status: respond(http.StatusCreated, `{}`),
+				notes:  respond(http.StatusOK, tt.notes),
+				create: respond(http.StatusCreated, `{}`),
+				update: respond(http.StatusOK, `{}`)})
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ta := NewTriggerAction(&TriggerOptions{GitCommit: "0123456789abcdef0123456789abcdef01234567", RuleName: "smoke"}, ioStreams)
+
+			ta.runID = "run-9"
+			ta.runURL = "https://app.waldo.com/applications/app-1/sessions/run-9"
+			ta.reporter = &gitLabReporter{
+				client:       api.NewGitLabClient(tt.projectID, "gl-token", false, ioStreams),
+				ioStreams:    ioStreams,
+				mergeRequest: "7"}
+
+			ta.finishTrigger()
+
+			if body := si.body(t, status); body["target_url"] != ta.runURL || body["name"] != "waldo/trigger" || body["state"] != "success" {
+				t.Errorf("commit status = %v", body)
+			}
+
+			key := update
+
+			if tt.wantCreate {
+				key = create
+			}
+
+			body, _ := si.body(t, key)["body"].(string)
+
+			if !strings.Contains(body, "run [run-9]("+ta.runURL+")") || !strings.Contains(body, "<!-- waldo:trigger -->") {
+				t.Errorf("note = %q", body)
+			}
+		})
+	}
+}
+
+func TestUpsertReportSection(t *testing.T) {
+	upload := &report{kind: "upload", summary: "upload 1"}
+	trigger := &report{kind: "trigger", summary: "trigger 1"}
+
+	body := upsertReportSection("", upload)
+
+	if want := reportCommentMarker + "\n### Waldo\n\n<!-- waldo:upload -->\nupload 1\n<!-- /waldo:upload -->\n"; body != want {
+		t.Fatalf("upsertReportSection() = %q, want %q", body, want)
+	}
+
+	body = upsertReportSection(body, trigger)
+	body = upsertReportSection(body, &report{kind: "upload", summary: "upload 2"})
+
+	want := reportCommentMarker + "\n### Waldo\n\n" +
+		"<!-- waldo:upload -->\nupload 2\n<!-- /waldo:upload -->\n\n" +
+		"<!-- waldo:trigger -->\ntrigger 1\n<!-- /waldo:trigger -->\n"
+
+	if body != want {
+		t.Errorf("upsertReportSection() = %q, want %q", body, want)
+	}
+
+	//
+	// A body without the marker (not ours) is replaced; an unterminated
+	// section is left alone and a new one appended:
+	//
+	if got := upsertReportSection("someone else's comment", upload); strings.Contains(got, "someone else") {
+		t.Errorf("upsertReportSection() kept foreign body: %q", got)
+	}
+
+	broken := reportCommentMarker + "\n<!-- waldo:upload -->\nold"
+
+	if got := upsertReportSection(broken, upload); !strings.HasSuffix(got, "<!-- waldo:upload -->\nupload 1\n<!-- /waldo:upload -->\n") {
+		t.Errorf("upsertReportSection() of unterminated section = %q", got)
+	}
+}
+
+func TestFormatReportLink(t *testing.T) {
+	tests := []struct {
+		id   string
+		url  string
+		want string
+	}{
+		{"bld-1", "https://app.waldo.com/b/bld-1", "build [bld-1](https://app.waldo.com/b/bld-1)"},
+		{"", "https://app.waldo.com/b/bld-1", "[build](https://app.waldo.com/b/bld-1)"},
+		{"bld-1", "", "build `bld-1`"},
+	}
+
+	for _, tt := range tests {
+		if got := formatReportLink("build", tt.id, tt.url); got != tt.want {
+			t.Errorf("formatReportLink(%q, %q) = %q, want %q", tt.id, tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package waldo
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+	"github.com/waldoapp/waldo-go-cli/waldo/artifact"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func (ua *UploadAction) applyTarget() error {
+	name := ua.options.Target
+
+	if len(name) == 0 {
+		return nil
+	}
+
+	target := ua.config.Targets[name]
+
+	if target == nil {
+		if len(ua.config.Targets) == 0 {
+			return fmt.Errorf("Unknown target: %q (no targets in project Waldo configuration)", name)
+		}
+
+		return fmt.Errorf("Unknown target: %q (expected one of: %v)", name, strings.Join(lib.SortedKeys(ua.config.Targets), ", "))
+	}
+
+	if len(target.Platform) > 0 && lib.ParsePlatform(target.Platform) != lib.PlatformAndroid && lib.ParsePlatform(target.Platform) != lib.PlatformIos {
+		return fmt.Errorf("Invalid platform for target %q: %q", name, target.Platform)
+	}
+
+	if target.PathScopedCommit && len(target.SourcePath) == 0 {
+		return fmt.Errorf("Target %q requires %q for %q", name, "source_path", "path_scoped_commit")
+	}
+
+	//
+	// Options given on the command line take precedence over those from the
+	// target (the app ID is applied later, as it depends on the kind of upload
+	// token):
+	//
+	if len(ua.options.VariantName) == 0 {
+		ua.options.VariantName = target.VariantName
+	}
+
+	if len(ua.options.FromEAS) > 0 && len(ua.options.EASPlatform) == 0 {
+		ua.options.EASPlatform = target.Platform
+	}
+
+	if len(ua.options.BuildPath) == 0 && len(ua.options.FromEAS) == 0 && len(ua.options.FromURL) == 0 && len(target.BuildPath) > 0 {
+		buildPath, err := ua.findTargetBuildPath(name, target.BuildPath)
+
+		if err != nil {
+			return err
+		}
+
+		ua.options.BuildPath = buildPath
+	}
+
+	ua.target = target
+
+	return nil
+}
+
+func (ua *UploadAction) findTargetBuildPath(name, pattern string) (string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(ua.config.BasePath(), pattern)
+	}
+
+	matches, err := filepath.Glob(pattern)
+
+	if err != nil {
+		return "", fmt.Errorf("Invalid build path for target %q: %q", name, pattern)
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("No build artifact found for target %q matching: %q", name, pattern)
+
+	case 1:
+		return matches[0], nil
+
+	default:
+		return "", fmt.Errorf("Multiple build artifacts found for target %q matching: %q\n  %v", name, pattern, strings.Join(matches, "\n  "))
+	}
+}
+
+func (ua *UploadAction) scopeGitCommit() {
+	if ua.target == nil || !ua.target.PathScopedCommit || ua.gitInfo == nil {
+		return
+	}
+
+	commit, err := git.LastCommitForPath(ua.gitInfo.Commit, ua.targetSourcePaths()...)
+
+	if err != nil {
+		ua.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Unable to find last commit for target %q, error: %v", ua.options.Target, err))
+
+		return
+	}
+
+	if len(commit) == 0 || commit == ua.gitInfo.Commit {
+		return
+	}
+
+	ua.options.GitCommit = commit
+
+	//
+	// The scoped commit is generally not at the tip of any branch; keep the
+	// branch (and detached state) of the checkout:
+	//
+	if gitInfo := inferGitInfo(commit); gitInfo != nil {
+		gitInfo.Branch = ua.gitInfo.Branch
+		gitInfo.Detached = ua.gitInfo.Detached
+
+		ua.gitInfo = gitInfo
+	}
+}
+
+func (ua *UploadAction) targetSourcePaths() []string {
+	if ua.target == nil || !ua.target.PathScopedCommit {
+		return nil
+	}
+
+	path := ua.target.SourcePath
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(ua.config.BasePath(), path)
+	}
+
+	return []string{path}
+}
+
+func (ua *UploadAction) validateTargetPlatform() error {
+	if ua.target == nil || len(ua.target.Platform) == 0 || len(ua.buildPath) == 0 {
+		return nil
+	}
+
+	expected := lib.ParsePlatform(ua.target.Platform)
+	actual := artifact.DetectKind(ua.buildPath).Platform()
+
+	//
+	// Archives are not unpacked until later; only check what can be detected
+	// up front:
+	//
+	if actual != lib.PlatformUnknown && actual != expected {
+		return fmt.Errorf("Build artifact for target %q is for %v, not %v: %q", ua.options.Target, actual, expected, ua.buildPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package waldo
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func TestUploadActionTargetAppID(t *testing.T) {
+	const (
+		apiToken = "u-0123456789abcdef0123456789abcdef"
+		ciToken  = "0123456789abcdef0123456789abcdef"
+	)
+
+	tests := []struct {
+		name        string
+		appID       string
+		uploadToken string
+		wantAppID   string
+		wantErr     string
+	}{
+		{name: "API token uses target app ID", uploadToken: apiToken, wantAppID: "app-0a1b2c3d4e5f"},
+		{name: "API token with explicit app ID", appID: "app-6789abcdef01", uploadToken: apiToken, wantAppID: "app-6789abcdef01"},
+		{name: "CI token ignores target app ID", uploadToken: ciToken},
+		{name: "CI token with explicit app ID", appID: "app-6789abcdef01", uploadToken: ciToken, wantErr: "not allowed with CI token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ua := NewUploadAction(&UploadOptions{AppID: tt.appID, Target: "android"}, ioStreams)
+
+			ua.config = &data.Configuration{
+				Targets: map[string]*data.TargetConfig{
+					"android": {AppID: "app-0a1b2c3d4e5f", VariantName: "release"}}}
+
+			if err := ua.applyTarget(); err != nil {
+				t.Fatalf("applyTarget() error = %v", err)
+			}
+
+			if ua.options.AppID != tt.appID {
+				t.Errorf("options.AppID = %q, want %q (unchanged)", ua.options.AppID, tt.appID)
+			}
+
+			ua.uploadToken = tt.uploadToken
+
+			appID, err := ua.detectAppID()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("detectAppID() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil || appID != tt.wantAppID {
+				t.Errorf("detectAppID() = %q, %v, want %q", appID, err, tt.wantAppID)
+			}
+		})
+	}
+}
@@ -1,20 +1,29 @@
 package waldo
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/ci"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
 	"github.com/waldoapp/waldo-go-cli/waldo/api"
 	"github.com/waldoapp/waldo-go-cli/waldo/data"
 )
 
 type TriggerOptions struct {
-	GitCommit     string
-	LegacyHelp    bool
-	LegacyVersion bool
-	RuleName      string
-	UploadToken   string
-	Verbose       bool
+	DryRun         bool
+	GitCommit      string
+	LegacyHelp     bool
+	LegacyVersion  bool
+	OverridePolicy string
+	Report         string
+	RuleName       string
+	UploadToken    string
+	Verbose        bool
 }
 
 type TriggerAction struct {
@@ -22,7 +31,19 @@ type TriggerAction struct {
 	options     *TriggerOptions
 	runtimeInfo *lib.RuntimeInfo
 
-	uploadToken string
+	agentUsage         agentUsage
+	branch             string
+	ciInfo             *ci.Info
+	config             *data.Configuration
+	gitInfo            *git.Info
+	overrideReason     string
+	reporter           reporter
+	rule               *data.TriggerRuleConfig
+	runID              string
+	runURL             string
+	tokenInEnvironment bool
+	uploadToken        string
+	variantName        string
 }
 
 //-----------------------------------------------------------------------------
@@ -49,8 +70,26 @@ func (ta *TriggerAction) Perform() error {
 		return err
 	}
 
+	if ta.options.Verbose || ta.options.DryRun {
+		ta.emitResolvedOptions()
+	}
+
+	if ta.rule != nil && ta.rule.Skip {
+		ta.ioStreams.Printf("\nSkipping trigger -- branch %q is mapped to no rule\n", ta.branch)
+
+		return nil
+	}
+
+	if ta.options.DryRun {
+		ta.ioStreams.Printf("\nDry run -- run not triggered\n")
+
+		return nil
+	}
+
+	assetVersion := ta.detectDownloadAssetVersion()
+
 	ad := api.NewAgentDownloader(
-		ta.detectDownloadAssetVersion(),
+		assetVersion,
 		data.CLIPrefix,
 		ta.detectDownloadVerbose(),
 		ta.ioStreams,
@@ -64,7 +103,24 @@ func (ta *TriggerAction) Perform() error {
 
 	defer ad.Cleanup()
 
-	return ta.executeAgent(path, ta.makeAgentArgs())
+	ta.agentUsage = probeAgentUsage(path, "trigger")
+
+	if len(ta.uploadToken) > 0 {
+		ta.tokenInEnvironment = ta.agentUsage.readsUploadToken(ta.ioStreams)
+	}
+
+	scanner := newAgentOutputScanner("Run")
+
+	if err := ta.executeAgent(path, ta.makeAgentArgs(), scanner); err != nil {
+		return err
+	}
+
+	ta.runID = scanner.id
+	ta.runURL = scanner.url
+
+	ta.finishTrigger()
+
+	return nil
 }
 
 //-----------------------------------------------------------------------------
@@ -85,13 +141,101 @@ func (ta *TriggerAction) detectDownloadVerbose() bool {
 	return false
 }
 
+func (ta *TriggerAction) detectGitInfo() {
+	//
+	// On pull request builds, prefer the real head commit over the synthetic
+	// merge commit checked out by the CI provider:
+	//
+	if ta.ciInfo != nil && len(ta.ciInfo.HeadCommit) > 0 && len(ta.options.GitCommit) == 0 {
+		ta.options.GitCommit = ta.ciInfo.HeadCommit
+		ta.branch = ta.ciInfo.Branch
+	}
+
+	ta.gitInfo = inferGitInfo(ta.options.GitCommit)
+
+	if ta.gitInfo != nil {
+		if len(ta.branch) == 0 {
+			ta.branch = ta.gitInfo.Branch
+		}
+
+		if len(ta.options.GitCommit) == 0 {
+			ta.options.GitCommit = ta.gitInfo.Commit
+		}
+	}
+
+	if ta.ciInfo != nil {
+		if len(ta.branch) == 0 {
+			ta.branch = ta.ciInfo.Branch
+		}
+
+		if len(ta.options.GitCommit) == 0 {
+			ta.options.GitCommit = ta.ciInfo.Commit
+		}
+	}
+}
+
+func (ta *TriggerAction) detectRule() error {
+	//
+	// An explicit rule name always takes precedence over the branch mapping:
+	//
+	if len(ta.options.RuleName) > 0 || len(ta.config.TriggerRules) == 0 {
+		return nil
+	}
+
+	if len(ta.branch) == 0 {
+		ta.ioStreams.Printf("\nNo git branch found -- trigger rule mapping not applied\n")
+
+		return nil
+	}
+
+	for _, rule := range ta.config.TriggerRules {
+		matched, err := path.Match(rule.Branch, ta.branch)
+
+		if err != nil {
+			return fmt.Errorf("Invalid branch pattern in trigger rule mapping: %q", rule.Branch)
+		}
+
+		if !matched {
+			continue
+		}
+
+		mapping := "default rule"
+
+		if len(rule.RuleName) > 0 {
+			mapping = fmt.Sprintf("rule %q", rule.RuleName)
+		}
+
+		if len(rule.VariantName) > 0 {
+			mapping += fmt.Sprintf(", variant %q", rule.VariantName)
+		}
+
+		if rule.Skip {
+			mapping = "skip"
+		}
+
+		ta.ioStreams.Printf("\nBranch %q matches %q -- %v\n", ta.branch, rule.Branch, mapping)
+
+		ta.options.RuleName = rule.RuleName
+		ta.rule = rule
+		ta.variantName = rule.VariantName
+
+		return nil
+	}
+
+	ta.ioStreams.Printf("\nBranch %q matches no trigger rule mapping\n", ta.branch)
+
+	return nil
+}
+
 func (ta *TriggerAction) detectUploadToken() (string, error) {
 	uploadToken := ta.options.UploadToken
 
 	var err error
 
 	if len(uploadToken) == 0 {
-		uploadToken = os.Getenv("WALDO_UPLOAD_TOKEN")
+		if uploadToken, err = lib.GetenvSecret("WALDO_UPLOAD_TOKEN"); err != nil {
+			return "", err
+		}
 	}
 
 	err = data.ValidateCIToken(uploadToken)
@@ -119,18 +263,112 @@ func (ta *TriggerAction) enrichEnvironment() lib.Environment {
 		env["WALDO_WRAPPER_VERSION_OVERRIDE"] = data.CLIVersion
 	}
 
+	metadata := make(map[string]string)
+
+	if ta.ciInfo != nil {
+		for key, value := range ta.ciInfo.Metadata() {
+			metadata[key] = value
+		}
+	}
+
+	if len(ta.overrideReason) > 0 {
+		metadata[policyOverrideMetadataKey] = ta.overrideReason
+	}
+
+	if len(metadata) > 0 {
+		//
+		// Metadata that this Waldo Agent does not accept would otherwise be
+		// dropped without a word:
+		//
+		if !ta.agentUsage.accepts("WALDO_TRIGGER_METADATA") {
+			ta.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Waldo Agent does not accept trigger metadata -- it will not be attached to the run"))
+		} else if metadata, err := json.Marshal(metadata); err == nil {
+			env["WALDO_TRIGGER_METADATA"] = string(metadata)
+		}
+	}
+
+	if len(ta.variantName) > 0 {
+		//
+		// Likewise, the run would otherwise quietly use the default variant:
+		//
+		if !ta.agentUsage.accepts("WALDO_TRIGGER_VARIANT_NAME") {
+			ta.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Waldo Agent does not accept a trigger variant name -- variant %q will not be applied to the run", ta.variantName))
+		} else {
+			env["WALDO_TRIGGER_VARIANT_NAME"] = ta.variantName
+		}
+	}
+
+	if len(ta.uploadToken) > 0 && ta.tokenInEnvironment {
+		env["WALDO_UPLOAD_TOKEN"] = ta.uploadToken
+	}
+
 	return env
 }
 
-func (ta *TriggerAction) executeAgent(path string, args []string) error {
+func (ta *TriggerAction) emitResolvedOptions() {
+	ta.ioStreams.Printf("\nResolved trigger options:\n")
+	ta.ioStreams.Printf("  git branch:       %v\n", ta.branch)
+	ta.ioStreams.Printf("  git commit:       %v\n", ta.options.GitCommit)
+	ta.ioStreams.Printf("  rule name:        %v\n", ta.options.RuleName)
+
+	if len(ta.variantName) > 0 {
+		ta.ioStreams.Printf("  variant name:     %v\n", ta.variantName)
+	}
+
+	emitGitInfo(ta.ioStreams, ta.gitInfo)
+	emitCIInfo(ta.ioStreams, ta.ciInfo)
+}
+
+func (ta *TriggerAction) executeAgent(path string, args []string, scanner *agentOutputScanner) error {
 	task := lib.NewTask(path, args...)
 
 	task.Env = ta.enrichEnvironment()
-	task.IOStreams = ta.ioStreams
+	task.IOStreams = ta.ioStreams.TeeOutput(scanner)
+
+	defer scanner.Flush()
 
 	return task.Execute()
 }
 
+func (ta *TriggerAction) finishTrigger() {
+	if ta.reporter == nil {
+		return
+	}
+
+	rpt := &report{
+		commit:     ta.options.GitCommit,
+		detailsURL: ta.runURL,
+		kind:       "trigger",
+		summary:    "**Waldo run triggered**",
+		title:      "Run triggered on Waldo"}
+
+	var details []string
+
+	if len(ta.runID) > 0 || len(ta.runURL) > 0 {
+		details = append(details, formatReportLink("run", ta.runID, ta.runURL))
+	}
+
+	if len(ta.options.GitCommit) > 0 {
+		details = append(details, fmt.Sprintf("commit `%v`", ta.options.GitCommit[:min(len(ta.options.GitCommit), 7)]))
+	}
+
+	if len(ta.options.RuleName) > 0 {
+		details = append(details, fmt.Sprintf("rule `%v`", ta.options.RuleName))
+	}
+
+	if len(ta.variantName) > 0 {
+		details = append(details, fmt.Sprintf("variant `%v`", ta.variantName))
+	}
+
+	if len(details) > 0 {
+		rpt.summary += " — " + strings.Join(details, " · ")
+	}
+
+	if err := ta.reporter.Report(rpt); err != nil {
+		emitReportError(ta.ioStreams, err)
+	}
+}
+
 func (ta *TriggerAction) makeAgentArgs() []string {
 	args := []string{"trigger"}
 
@@ -142,7 +380,7 @@ func (ta *TriggerAction) makeAgentArgs() []string {
 		args = append(args, "--rule_name", ta.options.RuleName)
 	}
 
-	if len(ta.uploadToken) > 0 {
+	if len(ta.uploadToken) > 0 && !ta.tokenInEnvironment {
 		args = append(args, "--upload_token", ta.uploadToken)
 	}
 
@@ -156,11 +394,42 @@ func (ta *TriggerAction) makeAgentArgs() []string {
 func (ta *TriggerAction) processOptions() error {
 	var err error
 
+	ta.config, err = data.LoadConfiguration()
+
+	if err != nil {
+		return err
+	}
+
+	ta.ciInfo = detectCIInfo()
+
+	ta.detectGitInfo()
+
+	if err = ta.detectRule(); err != nil {
+		return err
+	}
+
+	//
+	// Skipped triggers need no upload token, and are not subject to policy:
+	//
+	if ta.rule != nil && ta.rule.Skip {
+		return nil
+	}
+
 	ta.uploadToken, err = ta.detectUploadToken()
 
 	if err != nil {
 		return err
 	}
 
+	if err = ta.checkPolicy(); err != nil {
+		return err
+	}
+
+	ta.reporter, err = newReporter(ta.options.Report, ta.ciInfo, ta.gitInfo, ta.options.Verbose, ta.ioStreams)
+
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
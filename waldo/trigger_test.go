@@ -0,0 +1,121 @@
+package waldo
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func TestTriggerActionDetectRule(t *testing.T) {
+	rules := []*data.TriggerRuleConfig{
+		{Branch: "main", RuleName: "full"},
+		{Branch: "release/*", RuleName: "release", VariantName: "staging"},
+		{Branch: "release/legacy", RuleName: "legacy"},
+		{Branch: "dependabot/*", Skip: true},
+		{Branch: "*", RuleName: "smoke"}}
+
+	tests := []struct {
+		name        string
+		rules       []*data.TriggerRuleConfig
+		branch      string
+		ruleName    string
+		wantRule    int // index into rules, or -1 if none
+		wantName    string
+		wantVariant string
+		wantErr     string
+	}{
+		{name: "exact match", rules: rules, branch: "main", wantRule: 0, wantName: "full"},
+		{name: "glob match", rules: rules, branch: "release/1.2", wantRule: 1, wantName: "release", wantVariant: "staging"},
+		{name: "first match wins", rules: rules, branch: "release/legacy", wantRule: 1, wantName: "release", wantVariant: "staging"},
+		{name: "skip", rules: rules, branch: "dependabot/npm", wantRule: 3},
+		{name: "glob does not cross slash", rules: rules[:4], branch: "feature/x", wantRule: -1},
+		{name: "catch-all", rules: rules, branch: "feature", wantRule: 4, wantName: "smoke"},
+		{name: "no match", rules: rules[:2], branch: "feature", wantRule: -1},
+		{name: "no branch", rules: rules, wantRule: -1},
+		{name: "explicit rule name", rules: rules, branch: "main", ruleName: "custom", wantRule: -1, wantName: "custom"},
+		{name: "invalid pattern", rules: []*data.TriggerRuleConfig{{Branch: "[main"}}, branch: "main", wantRule: -1, wantErr: "Invalid branch pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+			ta := NewTriggerAction(&TriggerOptions{RuleName: tt.ruleName}, ioStreams)
+
+			ta.branch = tt.branch
+			ta.config = &data.Configuration{TriggerRules: tt.rules}
+
+			err := ta.detectRule()
+
+			if len(tt.wantErr) > 0 {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("detectRule() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("detectRule() error = %v", err)
+			}
+
+			var wantRule *data.TriggerRuleConfig
+
+			if tt.wantRule >= 0 {
+				wantRule = tt.rules[tt.wantRule]
+			}
+
+			if ta.rule != wantRule {
+				t.Errorf("rule = %+v, want %+v", ta.rule, wantRule)
+			}
+
+			if ta.options.RuleName != tt.wantName || ta.variantName != tt.wantVariant {
+				t.Errorf("rule name, variant = %q, %q, want %q, %q", ta.options.RuleName, ta.variantName, tt.wantName, tt.wantVariant)
+			}
+		})
+	}
+}
+
+func TestTriggerActionPerformSkip(t *testing.T) {
+	if !git.IsAvailable() {
+		t.Skip("git not found")
+	}
+
+	chdirTestProject(t, map[string]string{
+		".waldo/config.yml": "trigger_rules:\n  - branch: dependabot/*\n    skip: true\n"})
+
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch=dependabot/npm"},
+		{"-c", "user.name=Tester", "-c", "user.email=tester@example.com", "commit", "--allow-empty", "--quiet", "-m", "First"}} {
+		if _, err := git.Run(args...); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+
+	//
+	// Neither an upload token nor a reporter is needed to skip:
+	//
+	t.Setenv("WALDO_UPLOAD_TOKEN", "")
+	t.Setenv("WALDO_GITHUB_TOKEN", "")
+	t.Setenv("GITHUB_TOKEN", "")
+
+	var stdout strings.Builder
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), &stdout, io.Discard)
+
+	if err := NewTriggerAction(&TriggerOptions{Report: "github"}, ioStreams).Perform(); err != nil {
+		t.Fatalf("Perform() error = %v", err)
+	}
+
+	if !strings.Contains(stdout.String(), "Skipping trigger") {
+		t.Errorf("Perform() output = %q, want skipped", stdout.String())
+	}
+}
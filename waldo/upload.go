@@ -1,25 +1,45 @@
 package waldo
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/lib/ci"
+	"github.com/waldoapp/waldo-go-cli/lib/git"
 	"github.com/waldoapp/waldo-go-cli/waldo/api"
+	"github.com/waldoapp/waldo-go-cli/waldo/artifact"
 	"github.com/waldoapp/waldo-go-cli/waldo/data"
 )
 
 type UploadOptions struct {
-	AppID         string
-	BuildPath     string
-	GitBranch     string
-	GitCommit     string
-	LegacyHelp    bool
-	LegacyVersion bool
-	UploadToken   string
-	VariantName   string
-	Verbose       bool
+	AppID                string
+	BuildPath            string
+	DirectUpload         bool
+	DryRun               bool
+	EASPlatform          string
+	FromEAS              string
+	FromURL              string
+	FromURLHeaders       []string
+	FromURLToken         string
+	GitBranch            string
+	GitCommit            string
+	LegacyHelp           bool
+	LegacyVersion        bool
+	Metadata             []string
+	OverridePolicy       string
+	Profile              string
+	Report               string
+	ReleaseNotes         string
+	ReleaseNotesLimit    int
+	ReleaseNotesTemplate string
+	Target               string
+	UploadToken          string
+	VariantName          string
+	Verbose              bool
 }
 
 type UploadAction struct {
@@ -27,9 +47,22 @@ type UploadAction struct {
 	options     *UploadOptions
 	runtimeInfo *lib.RuntimeInfo
 
-	appID       string
-	buildPath   string
-	uploadToken string
+	agentUsage         agentUsage
+	appID              string
+	buildPath          string
+	buildVersion       string
+	ciInfo             *ci.Info
+	cleanups           []func()
+	config             *data.Configuration
+	description        string
+	easBuild           *api.EASBuild
+	gitInfo            *git.Info
+	metadata           map[string]string
+	reporter           reporter
+	result             *api.CompleteUploadResponse
+	target             *data.TargetConfig
+	tokenInEnvironment bool
+	uploadToken        string
 }
 
 //-----------------------------------------------------------------------------
@@ -56,8 +89,58 @@ func (ua *UploadAction) Perform() error {
 		return err
 	}
 
+	if ua.options.Verbose || ua.options.DryRun {
+		ua.emitResolvedOptions()
+	}
+
+	defer ua.cleanup()
+
+	//
+	// A local build is prepared before the project policy is checked, so that
+	// every violation is reported at once. A build from a URL or from EAS is
+	// not downloaded until the rest of the policy passes, so the parts of the
+	// policy that depend on it are checked separately, once it is prepared:
+	//
+	if !ua.fetchesBuild() {
+		if err := ua.prepareBuild(); err != nil {
+			return err
+		}
+	}
+
+	if err := ua.checkPolicy(); err != nil {
+		return err
+	}
+
+	if ua.options.DryRun {
+		ua.ioStreams.Printf("\nDry run -- build not uploaded\n")
+
+		return nil
+	}
+
+	if ua.fetchesBuild() {
+		if err := ua.prepareBuild(); err != nil {
+			return err
+		}
+
+		if err := ua.checkBuildPolicy(); err != nil {
+			return err
+		}
+	}
+
+	if ua.options.DirectUpload {
+		if err := ua.uploadDirect(); err != nil {
+			return err
+		}
+
+		ua.finishUpload()
+
+		return nil
+	}
+
+	assetVersion := ua.detectDownloadAssetVersion()
+
 	ad := api.NewAgentDownloader(
-		ua.detectDownloadAssetVersion(),
+		assetVersion,
 		data.CLIPrefix,
 		ua.detectDownloadVerbose(),
 		ua.ioStreams,
@@ -71,17 +154,69 @@ func (ua *UploadAction) Perform() error {
 
 	defer ad.Cleanup()
 
-	return ua.executeAgent(path, ua.makeAgentArgs())
+	ua.agentUsage = probeAgentUsage(path, "upload")
+
+	if len(ua.uploadToken) > 0 {
+		ua.tokenInEnvironment = ua.agentUsage.readsUploadToken(ua.ioStreams)
+	}
+
+	ua.checkAgentUsage()
+
+	scanner := newAgentOutputScanner("Build")
+
+	if err := ua.executeAgent(path, ua.makeAgentArgs(), scanner); err != nil {
+		return err
+	}
+
+	if len(scanner.id) > 0 || len(scanner.url) > 0 {
+		ua.result = &api.CompleteUploadResponse{
+			BuildID:  scanner.id,
+			BuildURL: scanner.url}
+	}
+
+	ua.finishUpload()
+
+	return nil
 }
 
 //-----------------------------------------------------------------------------
 
+func (ua *UploadAction) cleanup() {
+	for idx := len(ua.cleanups) - 1; idx >= 0; idx-- {
+		ua.cleanups[idx]()
+	}
+
+	ua.cleanups = nil
+}
+
+func (ua *UploadAction) checkAgentUsage() {
+	//
+	// Values that this Waldo Agent does not accept would otherwise be dropped
+	// without a word:
+	//
+	if len(ua.metadata) > 0 && !ua.agentUsage.accepts("WALDO_UPLOAD_METADATA") {
+		ua.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Waldo Agent does not accept upload metadata -- it will not be attached to the build (use --direct_upload to attach it)"))
+	}
+
+	if len(ua.description) > 0 && !ua.agentUsage.accepts("WALDO_UPLOAD_DESCRIPTION") {
+		ua.ioStreams.EmitError(data.CLIPrefix, fmt.Errorf("Waldo Agent does not accept a build description -- release notes will not be attached to the build (use --direct_upload to attach them)"))
+	}
+}
+
 func (ua *UploadAction) detectAppID() (string, error) {
 	appID := ua.options.AppID
 
 	var err error
 
 	if strings.HasPrefix(ua.uploadToken, "u-") {
+		//
+		// A CI token already identifies the app, so the app ID of the target
+		// only applies to an API token:
+		//
+		if len(appID) == 0 && ua.target != nil {
+			appID = ua.target.AppID
+		}
+
 		err = data.ValidateAppID(appID)
 	} else if len(appID) > 0 {
 		err = fmt.Errorf("Option %q not allowed with CI token", "--app_id")
@@ -97,11 +232,31 @@ func (ua *UploadAction) detectAppID() (string, error) {
 func (ua *UploadAction) detectBuildPath() (string, error) {
 	buildPath := ua.options.BuildPath
 
+	if len(ua.options.FromEAS) > 0 {
+		if len(buildPath) > 0 {
+			return "", fmt.Errorf("Option %q not allowed with build path", "--from_eas")
+		}
+
+		if len(ua.options.FromURL) > 0 {
+			return "", fmt.Errorf("Option %q not allowed with %q", "--from_eas", "--from_url")
+		}
+
+		return "", nil
+	}
+
+	if len(ua.options.FromURL) > 0 {
+		if len(buildPath) > 0 {
+			return "", fmt.Errorf("Option %q not allowed with build path", "--from_url")
+		}
+
+		return "", nil
+	}
+
 	if len(buildPath) == 0 {
 		return "", fmt.Errorf("No build path specified")
 	}
 
-	return buildPath, nil
+	return buildPath, validateBuildPath(buildPath)
 }
 
 func (ua *UploadAction) detectDownloadAssetVersion() string {
@@ -120,18 +275,52 @@ func (ua *UploadAction) detectDownloadVerbose() bool {
 	return false
 }
 
-func (ua *UploadAction) detectUploadToken() (string, error) {
-	uploadToken := ua.options.UploadToken
+func (ua *UploadAction) detectFromURLToken() string {
+	if token := ua.options.FromURLToken; len(token) > 0 {
+		return token
+	}
 
-	if len(uploadToken) == 0 {
-		uploadToken = os.Getenv("WALDO_UPLOAD_TOKEN")
+	return os.Getenv("WALDO_FROM_URL_TOKEN")
+}
+
+func (ua *UploadAction) detectMetadata() (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	if ua.ciInfo != nil {
+		for key, value := range ua.ciInfo.Metadata() {
+			metadata[key] = value
+		}
+	}
+
+	for key, value := range ua.config.Metadata {
+		if err := data.ValidateMetadataKey(key); err != nil {
+			return nil, err
+		}
+
+		metadata[key] = value
+	}
+
+	for _, item := range ua.options.Metadata {
+		key, value, err := data.ParseMetadataItem(item)
+
+		if err != nil {
+			return nil, err
+		}
+
+		metadata[key] = value
 	}
 
+	return metadata, nil
+}
+
+func (ua *UploadAction) detectUploadToken() (string, error) {
+	uploadToken := ua.options.UploadToken
+
 	if len(uploadToken) == 0 {
-		profile, _, err := data.SetupProfile(data.CreateKindNever)
+		var err error
 
-		if err == nil {
-			uploadToken = profile.APIToken
+		if uploadToken, _, err = findAPIToken(ua.options.Profile, ua.ioStreams); err != nil {
+			return "", err
 		}
 	}
 
@@ -142,6 +331,100 @@ func (ua *UploadAction) detectUploadToken() (string, error) {
 	return uploadToken, nil
 }
 
+func (ua *UploadAction) detectGitInfo() {
+	//
+	// A remote EAS build was not necessarily built from the local checkout;
+	// its own git metadata is applied once the build is fetched:
+	//
+	if fromEAS := ua.options.FromEAS; len(fromEAS) > 0 && fromEAS != "local" {
+		return
+	}
+
+	explicitCommit := len(ua.options.GitCommit) > 0
+
+	//
+	// On pull request builds, HEAD is often a synthetic merge commit that does
+	// not exist in the branch history; prefer the real head commit and source
+	// branch reported by the CI provider:
+	//
+	if ua.ciInfo != nil && len(ua.ciInfo.HeadCommit) > 0 && len(ua.options.GitCommit) == 0 {
+		ua.options.GitCommit = ua.ciInfo.HeadCommit
+
+		if len(ua.options.GitBranch) == 0 {
+			ua.options.GitBranch = ua.ciInfo.Branch
+		}
+	}
+
+	ua.gitInfo = inferGitInfo(ua.options.GitCommit)
+
+	if ua.gitInfo != nil {
+		if len(ua.options.GitBranch) == 0 {
+			ua.options.GitBranch = ua.gitInfo.Branch
+		}
+
+		if len(ua.options.GitCommit) == 0 {
+			ua.options.GitCommit = ua.gitInfo.Commit
+		}
+	}
+
+	//
+	// In a monorepo, a target may be identified by the last commit that
+	// touched its source directory rather than by the commit checked out:
+	//
+	if !explicitCommit {
+		ua.scopeGitCommit()
+	}
+
+	//
+	// CI checkouts are often detached (no branch) or not a full repository;
+	// fall back to what the CI provider reports:
+	//
+	if ua.ciInfo != nil {
+		if len(ua.options.GitBranch) == 0 {
+			ua.options.GitBranch = ua.ciInfo.Branch
+		}
+
+		if len(ua.options.GitCommit) == 0 {
+			ua.options.GitCommit = ua.ciInfo.Commit
+		}
+	}
+}
+
+func (ua *UploadAction) emitResolvedOptions() {
+	buildPath := ua.buildPath
+
+	switch {
+	case len(ua.options.FromEAS) > 0:
+		buildPath = "(from EAS build " + ua.options.FromEAS + ")"
+
+	case len(ua.options.FromURL) > 0:
+		buildPath = "(from " + ua.options.FromURL + ")"
+	}
+
+	ua.ioStreams.Printf("\nResolved upload options:\n")
+
+	if len(ua.options.Target) > 0 {
+		ua.ioStreams.Printf("  target:           %v\n", ua.options.Target)
+	}
+
+	ua.ioStreams.Printf("  app id:           %v\n", ua.appID)
+	ua.ioStreams.Printf("  build path:       %v\n", buildPath)
+	ua.ioStreams.Printf("  git branch:       %v\n", ua.options.GitBranch)
+	ua.ioStreams.Printf("  git commit:       %v\n", ua.options.GitCommit)
+	ua.ioStreams.Printf("  variant name:     %v\n", ua.options.VariantName)
+
+	for _, key := range lib.SortedKeys(ua.metadata) {
+		ua.ioStreams.Printf("  meta:             %v=%v\n", key, ua.metadata[key])
+	}
+
+	if len(ua.description) > 0 {
+		ua.ioStreams.Printf("  description:      %q\n", ua.description)
+	}
+
+	emitGitInfo(ua.ioStreams, ua.gitInfo)
+	emitCIInfo(ua.ioStreams, ua.ciInfo)
+}
+
 func (ua *UploadAction) enrichEnvironment() lib.Environment {
 	env := lib.CurrentEnvironment()
 
@@ -158,18 +441,94 @@ func (ua *UploadAction) enrichEnvironment() lib.Environment {
 		env["WALDO_WRAPPER_VERSION_OVERRIDE"] = data.CLIVersion
 	}
 
+	if len(ua.metadata) > 0 && ua.agentUsage.accepts("WALDO_UPLOAD_METADATA") {
+		if metadata, err := json.Marshal(ua.metadata); err == nil {
+			env["WALDO_UPLOAD_METADATA"] = string(metadata)
+		}
+	}
+
+	if len(ua.description) > 0 && ua.agentUsage.accepts("WALDO_UPLOAD_DESCRIPTION") {
+		env["WALDO_UPLOAD_DESCRIPTION"] = ua.description
+	}
+
+	if len(ua.uploadToken) > 0 && ua.tokenInEnvironment {
+		env["WALDO_UPLOAD_TOKEN"] = ua.uploadToken
+	}
+
 	return env
 }
 
-func (ua *UploadAction) executeAgent(path string, args []string) error {
+func (ua *UploadAction) executeAgent(path string, args []string, scanner *agentOutputScanner) error {
 	task := lib.NewTask(path, args...)
 
 	task.Env = ua.enrichEnvironment()
-	task.IOStreams = ua.ioStreams
+	task.IOStreams = ua.ioStreams.TeeOutput(scanner)
+
+	defer scanner.Flush()
 
 	return task.Execute()
 }
 
+func (ua *UploadAction) fetchBuild() (string, error) {
+	bf := api.NewBuildFetcher(
+		ua.options.FromURL,
+		ua.options.FromURLHeaders,
+		ua.detectFromURLToken(),
+		artifact.MakeExtractLimits(),
+		data.CLIPrefix,
+		ua.options.Verbose,
+		ua.ioStreams)
+
+	ua.cleanups = append(ua.cleanups, bf.Cleanup)
+
+	return bf.Fetch()
+}
+
+// fetchesBuild reports whether the build is downloaded, from a URL or from a
+// remote EAS build, rather than found locally.
+func (ua *UploadAction) fetchesBuild() bool {
+	return len(ua.options.FromURL) > 0 || (len(ua.options.FromEAS) > 0 && ua.options.FromEAS != "local")
+}
+
+func (ua *UploadAction) finishUpload() {
+	ua.recordUpload()
+
+	if ua.reporter == nil {
+		return
+	}
+
+	rpt := &report{
+		commit: ua.options.GitCommit,
+		kind:   "upload",
+		title:  "Build uploaded to Waldo"}
+
+	var details []string
+
+	if ua.result != nil {
+		rpt.detailsURL = ua.result.BuildURL
+
+		details = append(details, formatReportLink("build", ua.result.BuildID, ua.result.BuildURL))
+	}
+
+	if len(ua.options.GitCommit) > 0 {
+		details = append(details, fmt.Sprintf("commit `%v`", ua.options.GitCommit[:min(len(ua.options.GitCommit), 7)]))
+	}
+
+	if len(ua.options.VariantName) > 0 {
+		details = append(details, fmt.Sprintf("variant `%v`", ua.options.VariantName))
+	}
+
+	rpt.summary = "**Build uploaded to Waldo**"
+
+	if len(details) > 0 {
+		rpt.summary += " — " + strings.Join(details, " · ")
+	}
+
+	if err := ua.reporter.Report(rpt); err != nil {
+		emitReportError(ua.ioStreams, err)
+	}
+}
+
 func (ua *UploadAction) makeAgentArgs() []string {
 	args := []string{"upload"}
 
@@ -185,7 +544,7 @@ func (ua *UploadAction) makeAgentArgs() []string {
 		args = append(args, "--git_commit", ua.options.GitCommit)
 	}
 
-	if len(ua.uploadToken) > 0 {
+	if len(ua.uploadToken) > 0 && !ua.tokenInEnvironment {
 		args = append(args, "--upload_token", ua.uploadToken)
 	}
 
@@ -204,15 +563,76 @@ func (ua *UploadAction) makeAgentArgs() []string {
 	return args
 }
 
+func (ua *UploadAction) prepareBuild() error {
+	var (
+		downloaded bool
+		sourcePath string
+		err        error
+	)
+
+	switch {
+	case len(ua.options.FromEAS) > 0:
+		sourcePath, err = ua.fetchEASBuild()
+		downloaded = ua.options.FromEAS != "local"
+
+	case len(ua.options.FromURL) > 0:
+		sourcePath, err = ua.fetchBuild()
+		downloaded = true
+
+	default:
+		sourcePath = ua.buildPath
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if err := ua.unpackBuild(sourcePath); err != nil {
+		return err
+	}
+
+	//
+	// A downloaded archive is no longer needed once extracted -- remove it now
+	// rather than keep both it and its contents on disk during the upload:
+	//
+	if downloaded && sourcePath != ua.buildPath && lib.IsRegularFile(sourcePath) {
+		os.Remove(sourcePath)
+	}
+
+	return nil
+}
+
 func (ua *UploadAction) processOptions() error {
 	var err error
 
+	ua.config, err = data.LoadConfiguration()
+
+	if err != nil {
+		return err
+	}
+
+	if err = ua.applyTarget(); err != nil {
+		return err
+	}
+
+	ua.ciInfo = detectCIInfo()
+
+	ua.detectGitInfo()
+
+	if err = ua.resolveEASBuild(); err != nil {
+		return err
+	}
+
 	ua.buildPath, err = ua.detectBuildPath()
 
 	if err != nil {
 		return err
 	}
 
+	if err = ua.validateTargetPlatform(); err != nil {
+		return err
+	}
+
 	ua.uploadToken, err = ua.detectUploadToken()
 
 	if err != nil {
@@ -225,5 +645,106 @@ func (ua *UploadAction) processOptions() error {
 		return err
 	}
 
+	ua.metadata, err = ua.detectMetadata()
+
+	if err != nil {
+		return err
+	}
+
+	ua.description, err = ua.detectDescription()
+
+	if err != nil {
+		return err
+	}
+
+	ua.reporter, err = newReporter(ua.options.Report, ua.ciInfo, ua.gitInfo, ua.options.Verbose, ua.ioStreams)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (ua *UploadAction) unpackBuild(sourcePath string) error {
+	u := artifact.NewUnpacker(
+		sourcePath,
+		findBundletoolPath(ua.config),
+		ua.options.Verbose,
+		ua.ioStreams)
+
+	ua.cleanups = append(ua.cleanups, u.Cleanup)
+
+	buildPath, err := u.Unpack()
+
+	if err != nil {
+		return err
+	}
+
+	ua.buildPath = buildPath
+
+	return nil
+}
+
+func (ua *UploadAction) uploadDirect() error {
+	info := &api.UploadInfo{
+		AppID:       ua.appID,
+		Description: ua.description,
+		GitBranch:   ua.options.GitBranch,
+		GitCommit:   ua.options.GitCommit,
+		Metadata:    ua.metadata,
+		VariantName: ua.options.VariantName}
+
+	bu := api.NewBuildUploader(
+		ua.buildPath,
+		ua.uploadToken,
+		info,
+		data.CLIPrefix,
+		ua.options.Verbose,
+		ua.ioStreams)
+
+	defer bu.Cleanup()
+
+	result, err := bu.Upload()
+
+	if err != nil {
+		return err
+	}
+
+	ua.result = result
+
+	if len(result.BuildURL) > 0 {
+		ua.ioStreams.Printf("\nBuild %q successfully uploaded to Waldo -- %v\n", result.BuildID, result.BuildURL)
+	} else {
+		ua.ioStreams.Printf("\nBuild %q successfully uploaded to Waldo\n", result.BuildID)
+	}
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+// findBundletoolPath returns the path to bundletool (used to convert an AAB to
+// an APK) from WALDO_BUNDLETOOL_PATH or else the project Waldo configuration,
+// where it is relative to the project root.
+func findBundletoolPath(cfg *data.Configuration) string {
+	if path := os.Getenv("WALDO_BUNDLETOOL_PATH"); len(path) > 0 {
+		return path
+	}
+
+	path := cfg.BundletoolPath
+
+	if len(path) > 0 && !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.BasePath(), path)
+	}
+
+	return path
+}
+
+func validateBuildPath(buildPath string) error {
+	if !lib.FileExists(buildPath) {
+		return fmt.Errorf("Build path not found: %q", buildPath)
+	}
+
 	return nil
 }
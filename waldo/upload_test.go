@@ -0,0 +1,67 @@
+package waldo
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+func TestUploadActionFetchRemovesArchive(t *testing.T) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for name, contents := range map[string]string{"Sample.app/Info.plist": testInfoPlist, "Sample.app/Sample": "binary"} {
+		w, _ := zw.Create(name)
+
+		w.Write([]byte(contents))
+	}
+
+	zw.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(buf.Bytes())
+	}))
+
+	defer server.Close()
+
+	tmpPath := t.TempDir()
+
+	t.Setenv("TMPDIR", tmpPath)
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+	ua := NewUploadAction(&UploadOptions{FromURL: server.URL + "/Sample.zip"}, ioStreams)
+
+	ua.config = &data.Configuration{}
+
+	defer ua.cleanup()
+
+	if err := ua.prepareBuild(); err != nil {
+		t.Fatalf("prepareBuild() error = %v", err)
+	}
+
+	if filepath.Base(ua.buildPath) != "Sample.app" || !lib.IsDirectory(ua.buildPath) {
+		t.Errorf("buildPath = %q, want unpacked Sample.app", ua.buildPath)
+	}
+
+	//
+	// Only the extracted build remains on disk, not the downloaded archive:
+	//
+	if matches, _ := filepath.Glob(filepath.Join(tmpPath, "*", "Sample.zip")); len(matches) > 0 {
+		t.Errorf("downloaded archive not removed: %v", matches)
+	}
+
+	if _, err := os.Stat(filepath.Join(ua.buildPath, "Info.plist")); err != nil {
+		t.Errorf("extracted build missing: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package waldo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+	"github.com/waldoapp/waldo-go-cli/waldo/api"
+	"github.com/waldoapp/waldo-go-cli/waldo/data"
+)
+
+type WhoamiOptions struct {
+	JSON    bool
+	Profile string
+	Verbose bool
+}
+
+type WhoamiAction struct {
+	ioStreams   *lib.IOStreams
+	options     *WhoamiOptions
+	runtimeInfo *lib.RuntimeInfo
+}
+
+type whoamiResult struct {
+	Email       string `json:"email,omitempty"`
+	FirstName   string `json:"firstName,omitempty"`
+	FullName    string `json:"fullName,omitempty"`
+	LastName    string `json:"lastName,omitempty"`
+	Token       string `json:"token"`     // masked
+	TokenKind   string `json:"tokenKind"` // "api" or "ci"
+	TokenSource string `json:"tokenSource"`
+	UserID      string `json:"userId,omitempty"`
+}
+
+//-----------------------------------------------------------------------------
+
+func NewWhoamiAction(options *WhoamiOptions, ioStreams *lib.IOStreams) *WhoamiAction {
+	runtimeInfo := lib.DetectRuntimeInfo()
+
+	return &WhoamiAction{
+		ioStreams:   ioStreams,
+		options:     options,
+		runtimeInfo: runtimeInfo}
+}
+
+//-----------------------------------------------------------------------------
+
+func (wa *WhoamiAction) Perform() error {
+	apiToken, source, err := findAPIToken(wa.options.Profile, wa.ioStreams)
+
+	if err != nil {
+		return err
+	}
+
+	if len(apiToken) == 0 {
+		return errors.New("Not authenticated -- use “waldo auth” to authenticate")
+	}
+
+	result := &whoamiResult{
+		Token:       maskToken(apiToken),
+		TokenSource: source}
+
+	//
+	// WALDO_UPLOAD_TOKEN may hold a CI token, which identifies an app rather
+	// than a user (and is rejected by the user endpoint); report it as such:
+	//
+	if data.ValidateCIToken(apiToken) == nil {
+		result.TokenKind = "ci"
+	} else if err := data.ValidateAPIToken(apiToken); err != nil {
+		return fmt.Errorf("%v (from %v)", err, source)
+	} else {
+		aur, err := api.AuthenticateUser(apiToken, wa.options.Verbose, wa.ioStreams)
+
+		if err != nil {
+			return err
+		}
+
+		result.Email = aur.Email
+		result.FirstName = aur.FirstName
+		result.FullName = aur.FullName()
+		result.LastName = aur.LastName
+		result.TokenKind = "api"
+		result.UserID = aur.UserID
+	}
+
+	if wa.options.JSON {
+		return wa.emitJSON(result)
+	}
+
+	wa.emitText(result)
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (wa *WhoamiAction) emitJSON(result *whoamiResult) error {
+	output, err := json.MarshalIndent(result, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	wa.ioStreams.Printf("%v\n", string(output))
+
+	return nil
+}
+
+func (wa *WhoamiAction) emitText(result *whoamiResult) {
+	wa.ioStreams.Printf("\n")
+
+	if result.TokenKind == "ci" {
+		wa.ioStreams.Printf("  CI token (identifies an app, not a user)\n")
+		wa.ioStreams.Printf("  token:            %v\n", result.Token)
+		wa.ioStreams.Printf("  token source:     %v\n", result.TokenSource)
+
+		return
+	}
+
+	wa.ioStreams.Printf("  user:             %v\n", result.FullName)
+
+	if len(result.Email) > 0 {
+		wa.ioStreams.Printf("  email:            %v\n", result.Email)
+	}
+
+	wa.ioStreams.Printf("  user id:          %v\n", result.UserID)
+	wa.ioStreams.Printf("  token:            %v\n", result.Token)
+	wa.ioStreams.Printf("  token source:     %v\n", result.TokenSource)
+}
+
+//-----------------------------------------------------------------------------
+
+func maskToken(token string) string {
+	if len(token) <= 8 {
+		return "…"
+	}
+
+	return token[:2] + "…" + token[len(token)-4:]
+}
@@ -0,0 +1,76 @@
+package waldo
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+func TestWhoamiActionUploadToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		token         string
+		wantKind      string
+		wantUserID    string
+		wantRequested bool
+	}{
+		{"API token", "u-0123456789abcdef0123456789abcdef", "api", "usr-1", true},
+		{"CI token", "0123456789abcdef0123456789abcdef", "ci", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requested := false
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requested = true
+
+				if r.Header.Get("Authorization") != "Token "+tt.token {
+					w.WriteHeader(http.StatusUnauthorized)
+
+					return
+				}
+
+				json.NewEncoder(w).Encode(map[string]string{"id": "usr-1", "email": "jo@example.com", "firstName": "Jo", "lastName": "Doe"})
+			}))
+
+			defer server.Close()
+
+			t.Setenv("HOME", t.TempDir())
+			t.Setenv("WALDO_API_AUTHENTICATE_USER_ENDPOINT_OVERRIDE", server.URL)
+			t.Setenv("WALDO_UPLOAD_TOKEN", tt.token)
+
+			var stdout bytes.Buffer
+
+			ioStreams := lib.NewIOStreams(strings.NewReader(""), &stdout, io.Discard)
+
+			if err := NewWhoamiAction(&WhoamiOptions{JSON: true}, ioStreams).Perform(); err != nil {
+				t.Fatalf("Perform() error = %v", err)
+			}
+
+			var result whoamiResult
+
+			if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+				t.Fatalf("output = %q: %v", stdout.String(), err)
+			}
+
+			if result.TokenKind != tt.wantKind || result.UserID != tt.wantUserID || result.TokenSource != "WALDO_UPLOAD_TOKEN environment variable" {
+				t.Errorf("result = %+v", result)
+			}
+
+			if strings.Contains(stdout.String(), tt.token) {
+				t.Errorf("output contains unmasked token: %q", stdout.String())
+			}
+
+			if requested != tt.wantRequested {
+				t.Errorf("user endpoint requested = %v, want %v", requested, tt.wantRequested)
+			}
+		})
+	}
+}
@@ -0,0 +1,506 @@
+package waldo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+type CIInitOptions struct {
+	Force    bool
+	Platform string
+	Provider string
+	Trigger  bool
+}
+
+type CIInitAction struct {
+	ioStreams *lib.IOStreams
+	options   *CIInitOptions
+}
+
+//-----------------------------------------------------------------------------
+
+type ciBuildPlan struct {
+	AppPath     string
+	Commands    []string
+	Platform    lib.Platform
+	ProjectType string
+	Trigger     bool
+}
+
+// ciProvider describes where a CI provider's workflow goes. A shared output
+// path holds the project's whole pipeline, so an existing file there is never
+// overwritten (not even with --force); the workflow is printed to be merged by
+// hand instead.
+type ciProvider struct {
+	name       string
+	outputPath string
+	note       string
+	shared     bool
+	template   string
+}
+
+var ciProviders = map[string]*ciProvider{
+	"bitrise": {
+		name:       "Bitrise",
+		outputPath: "bitrise.yml",
+		shared:     true,
+		template:   bitriseWorkflowTemplate},
+	"circleci": {
+		name:       "CircleCI",
+		outputPath: filepath.Join(".circleci", "config.yml"),
+		shared:     true,
+		template:   circleCIWorkflowTemplate},
+	"github": {
+		name:       "GitHub Actions",
+		outputPath: filepath.Join(".github", "workflows", "waldo.yml"),
+		template:   gitHubWorkflowTemplate},
+	"gitlab": {
+		name:       "GitLab CI",
+		outputPath: filepath.Join(".gitlab", "ci", "waldo.gitlab-ci.yml"),
+		note:       "Include it from your .gitlab-ci.yml:\n\n  include:\n    - local: .gitlab/ci/waldo.gitlab-ci.yml\n",
+		template:   gitLabWorkflowTemplate}}
+
+//-----------------------------------------------------------------------------
+
+func NewCIInitAction(options *CIInitOptions, ioStreams *lib.IOStreams) *CIInitAction {
+	return &CIInitAction{
+		ioStreams: ioStreams,
+		options:   options}
+}
+
+//-----------------------------------------------------------------------------
+
+func (cia *CIInitAction) Perform() error {
+	provider, err := cia.detectProvider()
+
+	if err != nil {
+		return err
+	}
+
+	plan, err := cia.detectBuildPlan()
+
+	if err != nil {
+		return err
+	}
+
+	tmpl, err := template.New(provider.name).Delims("[[", "]]").Parse(provider.template)
+
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+
+	if err := tmpl.Execute(&sb, plan); err != nil {
+		return err
+	}
+
+	outputPath := provider.outputPath
+
+	if provider.shared && lib.FileExists(outputPath) {
+		cia.ioStreams.Printf("\n%q already exists; merge this %v workflow for %v %v project into it by hand:\n\n%v", outputPath, provider.name, plan.ProjectType, plan.Platform, sb.String())
+	} else {
+		if err := cia.writeWorkflow(provider, sb.String()); err != nil {
+			return err
+		}
+
+		cia.ioStreams.Printf("\nWrote %v workflow for %v %v project to %q\n", provider.name, plan.ProjectType, plan.Platform, outputPath)
+
+		if len(provider.note) > 0 {
+			cia.ioStreams.Printf("\n%v", provider.note)
+		}
+	}
+
+	cia.ioStreams.Printf("\nBefore running it, review the build commands and add your Waldo upload token as a secret named WALDO_UPLOAD_TOKEN.\n")
+
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+
+func (cia *CIInitAction) detectBuildPlan() (*ciBuildPlan, error) {
+	projectType, iosDir, androidDir := detectProjectType()
+
+	if len(projectType) == 0 {
+		return nil, fmt.Errorf("Unable to detect project type (no Xcode, Gradle, Flutter, or React Native project found)")
+	}
+
+	if projectType == "React Native" && len(iosDir) == 0 && len(androidDir) == 0 {
+		return nil, fmt.Errorf("No native iOS or Android project found (for Expo projects, run “npx expo prebuild” first)")
+	}
+
+	platform, err := cia.detectPlatform(iosDir, androidDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &ciBuildPlan{
+		Platform:    platform,
+		ProjectType: projectType,
+		Trigger:     cia.options.Trigger}
+
+	switch {
+	case projectType == "Flutter" && platform == lib.PlatformIos:
+		plan.AppPath = "build/ios/iphonesimulator/Runner.app"
+		plan.Commands = []string{"flutter pub get", "flutter build ios --simulator --debug"}
+
+	case projectType == "Flutter":
+		plan.AppPath = "build/app/outputs/flutter-apk/app-debug.apk"
+		plan.Commands = []string{"flutter pub get", "flutter build apk --debug"}
+
+	case platform == lib.PlatformIos:
+		plan.AppPath, plan.Commands = makeXcodeBuild(iosDir)
+
+		if projectType == "React Native" {
+			plan.Commands = append([]string{"npm ci", "(cd ios && pod install)"}, plan.Commands...)
+		}
+
+	default:
+		plan.AppPath, plan.Commands = makeGradleBuild(androidDir)
+
+		if projectType == "React Native" {
+			plan.Commands = append([]string{"npm ci"}, plan.Commands...)
+		}
+	}
+
+	return plan, nil
+}
+
+func (cia *CIInitAction) detectPlatform(iosDir, androidDir string) (lib.Platform, error) {
+	switch strings.ToLower(cia.options.Platform) {
+	case "":
+		if len(iosDir) > 0 {
+			return lib.PlatformIos, nil
+		}
+
+		return lib.PlatformAndroid, nil
+
+	case "android":
+		if len(androidDir) == 0 {
+			return lib.PlatformUnknown, fmt.Errorf("No Android project found")
+		}
+
+		return lib.PlatformAndroid, nil
+
+	case "ios":
+		if len(iosDir) == 0 {
+			return lib.PlatformUnknown, fmt.Errorf("No iOS project found")
+		}
+
+		return lib.PlatformIos, nil
+
+	default:
+		return lib.PlatformUnknown, fmt.Errorf("Invalid platform: %q", cia.options.Platform)
+	}
+}
+
+func (cia *CIInitAction) detectProvider() (*ciProvider, error) {
+	name := strings.ToLower(cia.options.Provider)
+
+	if len(name) == 0 {
+		return nil, fmt.Errorf("No CI provider specified")
+	}
+
+	provider := ciProviders[name]
+
+	if provider == nil {
+		return nil, fmt.Errorf("Invalid CI provider: %q (must be one of: %v)", cia.options.Provider, strings.Join(lib.SortedKeys(ciProviders), ", "))
+	}
+
+	return provider, nil
+}
+
+// writeWorkflow creates the workflow file, atomically refusing to replace an
+// existing one unless --force is given for a provider whose file is not shared.
+func (cia *CIInitAction) writeWorkflow(provider *ciProvider, contents string) error {
+	outputPath := provider.outputPath
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+
+	if cia.options.Force && !provider.shared {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(outputPath, flags, 0644)
+
+	if errors.Is(err, fs.ErrExist) {
+		if provider.shared {
+			return fmt.Errorf("File already exists: %q", outputPath)
+		}
+
+		return fmt.Errorf("File already exists: %q (use --force to overwrite)", outputPath)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.WriteString(contents); err != nil {
+		file.Close()
+
+		return err
+	}
+
+	return file.Close()
+}
+
+//-----------------------------------------------------------------------------
+
+// detectProjectType returns the project type along with the directories
+// holding the iOS and Android projects (empty if absent).
+func detectProjectType() (string, string, string) {
+	var iosDir, androidDir string
+
+	for _, dir := range []string{".", "ios"} {
+		if len(findXcodeProject(dir)) > 0 {
+			iosDir = dir
+
+			break
+		}
+	}
+
+	for _, dir := range []string{".", "android"} {
+		if lib.IsRegularFile(filepath.Join(dir, "gradlew")) || lib.IsRegularFile(filepath.Join(dir, "build.gradle")) || lib.IsRegularFile(filepath.Join(dir, "build.gradle.kts")) {
+			androidDir = dir
+
+			break
+		}
+	}
+
+	switch {
+	case lib.IsRegularFile("pubspec.yaml"):
+		return "Flutter", iosDir, androidDir
+
+	case hasNodeDependency("react-native") || hasNodeDependency("expo"):
+		return "React Native", iosDir, androidDir
+
+	case len(iosDir) > 0 || len(androidDir) > 0:
+		return "native", iosDir, androidDir
+
+	default:
+		return "", iosDir, androidDir
+	}
+}
+
+func findXcodeProject(dir string) string {
+	for _, pattern := range []string{"*.xcworkspace", "*.xcodeproj"} {
+		if matches, _ := filepath.Glob(filepath.Join(dir, pattern)); len(matches) > 0 {
+			return matches[0]
+		}
+	}
+
+	return ""
+}
+
+func hasNodeDependency(name string) bool {
+	data, err := os.ReadFile("package.json")
+
+	if err != nil {
+		return false
+	}
+
+	var pkg struct {
+		Dependencies    map[string]string `json:"dependencies"`
+		DevDependencies map[string]string `json:"devDependencies"`
+	}
+
+	if json.Unmarshal(data, &pkg) != nil {
+		return false
+	}
+
+	_, found := pkg.Dependencies[name]
+
+	if !found {
+		_, found = pkg.DevDependencies[name]
+	}
+
+	return found
+}
+
+func makeGradleBuild(dir string) (string, []string) {
+	appPath := filepath.ToSlash(filepath.Join(dir, "app", "build", "outputs", "apk", "debug", "app-debug.apk"))
+
+	if dir == "." {
+		return appPath, []string{"./gradlew assembleDebug"}
+	}
+
+	return appPath, []string{fmt.Sprintf("(cd %v && ./gradlew assembleDebug)", dir)}
+}
+
+func makeXcodeBuild(dir string) (string, []string) {
+	project := findXcodeProject(dir)
+	scheme := strings.TrimSuffix(filepath.Base(project), filepath.Ext(project))
+
+	kind := "-project"
+
+	if filepath.Ext(project) == ".xcworkspace" {
+		kind = "-workspace"
+	}
+
+	command := fmt.Sprintf("xcodebuild %v %q -scheme %q -configuration Release -sdk iphonesimulator -derivedDataPath build/waldo build",
+		kind,
+		filepath.ToSlash(project),
+		scheme)
+
+	return fmt.Sprintf("build/waldo/Build/Products/Release-iphonesimulator/%v.app", scheme), []string{command}
+}
+
+//-----------------------------------------------------------------------------
+
+const gitHubWorkflowTemplate = `name: Waldo
+
+on:
+  push:
+    branches: [main]
+  pull_request:
+
+jobs:
+  waldo:
+    runs-on: [[if eq .Platform "iOS"]]macos-latest[[else]]ubuntu-latest[[end]]
+    steps:
+      - uses: actions/checkout@v4
+[[- if eq .ProjectType "Flutter"]]
+      - uses: subosito/flutter-action@v2
+[[- else if eq .ProjectType "React Native"]]
+      - uses: actions/setup-node@v4
+        with:
+          node-version: lts/*
+[[- end]]
+[[- if eq .Platform "Android"]]
+      - uses: actions/setup-java@v4
+        with:
+          distribution: temurin
+          java-version: "17"
+[[- end]]
+      - name: Build [[.Platform]] artifact
+        run: |
+[[- range .Commands]]
+          [[.]]
+[[- end]]
+      - name: Install Waldo CLI
+        run: bash -c "$(curl -fLs https://github.com/waldoapp/waldo-go-cli/raw/master/install-waldo.sh)"
+      - name: Upload build to Waldo
+        env:
+          WALDO_UPLOAD_TOKEN: ${{ secrets.WALDO_UPLOAD_TOKEN }}
+        run: waldo upload "[[.AppPath]]"
+[[- if .Trigger]]
+      - name: Trigger Waldo run
+        env:
+          WALDO_UPLOAD_TOKEN: ${{ secrets.WALDO_UPLOAD_TOKEN }}
+        run: waldo trigger
+[[- end]]
+`
+
+const gitLabWorkflowTemplate = `# WALDO_UPLOAD_TOKEN must be defined as a masked CI/CD variable.
+
+waldo:
+  stage: test
+[[- if eq .Platform "iOS"]]
+  tags:
+    - saas-macos-medium-m1
+  image: macos-14-xcode-15
+[[- else if eq .ProjectType "Flutter"]]
+  image: ghcr.io/cirruslabs/flutter:stable
+[[- else]]
+  image: eclipse-temurin:17-jdk
+[[- end]]
+  script:
+[[- range .Commands]]
+    - [[.]]
+[[- end]]
+    - bash -c "$(curl -fLs https://github.com/waldoapp/waldo-go-cli/raw/master/install-waldo.sh)"
+    - waldo upload "[[.AppPath]]"
+[[- if .Trigger]]
+    - waldo trigger
+[[- end]]
+`
+
+const bitriseWorkflowTemplate = `---
+format_version: "13"
+default_step_lib_source: https://github.com/bitrise-io/bitrise-steplib.git
+project_type: [[if eq .ProjectType "Flutter"]]flutter[[else if eq .ProjectType "React Native"]]react-native[[else if eq .Platform "iOS"]]ios[[else]]android[[end]]
+
+# WALDO_UPLOAD_TOKEN must be defined as a Bitrise secret.
+
+workflows:
+  waldo:
+    steps:
+      - git-clone@8: {}
+      - script@1:
+          title: Build [[.Platform]] artifact
+          inputs:
+            - content: |-
+                set -euo pipefail
+[[- range .Commands]]
+                [[.]]
+[[- end]]
+      - script@1:
+          title: Upload build to Waldo
+          inputs:
+            - content: |-
+                set -euo pipefail
+                bash -c "$(curl -fLs https://github.com/waldoapp/waldo-go-cli/raw/master/install-waldo.sh)"
+                waldo upload "[[.AppPath]]"
+[[- if .Trigger]]
+                waldo trigger
+[[- end]]
+`
+
+const circleCIWorkflowTemplate = `version: 2.1
+
+# WALDO_UPLOAD_TOKEN must be defined in a project environment variable or
+# context.
+
+jobs:
+  waldo:
+[[- if eq .Platform "iOS"]]
+    macos:
+      xcode: 15.4.0
+[[- else if eq .ProjectType "Flutter"]]
+    docker:
+      - image: ghcr.io/cirruslabs/flutter:stable
+[[- else if eq .ProjectType "React Native"]]
+    docker:
+      - image: cimg/android:2024.01-node
+[[- else]]
+    docker:
+      - image: cimg/android:2024.01
+[[- end]]
+    steps:
+      - checkout
+      - run:
+          name: Build [[.Platform]] artifact
+          command: |
+[[- range .Commands]]
+            [[.]]
+[[- end]]
+      - run:
+          name: Install Waldo CLI
+          command: bash -c "$(curl -fLs https://github.com/waldoapp/waldo-go-cli/raw/master/install-waldo.sh)"
+      - run:
+          name: Upload build to Waldo
+          command: waldo upload "[[.AppPath]]"
+[[- if .Trigger]]
+      - run:
+          name: Trigger Waldo run
+          command: waldo trigger
+[[- end]]
+
+workflows:
+  waldo:
+    jobs:
+      - waldo
+`
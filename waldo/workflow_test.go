@@ -0,0 +1,207 @@
+package waldo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/waldoapp/waldo-go-cli/lib"
+)
+
+// chdirTestProject changes into a new directory holding the given project
+// files (a name ending in “/” is a directory).
+func chdirTestProject(t *testing.T, files map[string]string) {
+	dir := t.TempDir()
+
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+
+		if strings.HasSuffix(name, "/") {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, _ := os.Getwd()
+
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestCIInitActionPerform(t *testing.T) {
+	projects := []struct {
+		name         string
+		files        map[string]string
+		platform     string
+		wantCommands []string
+		wantAppPath  string
+	}{
+		{
+			name:         "native iOS",
+			files:        map[string]string{"Sample.xcodeproj/": ""},
+			wantCommands: []string{`xcodebuild -project "Sample.xcodeproj" -scheme "Sample" -configuration Release -sdk iphonesimulator -derivedDataPath build/waldo build`},
+			wantAppPath:  "build/waldo/Build/Products/Release-iphonesimulator/Sample.app"},
+		{
+			name:         "native Android",
+			files:        map[string]string{"gradlew": "", "app/build.gradle": ""},
+			wantCommands: []string{"./gradlew assembleDebug"},
+			wantAppPath:  "app/build/outputs/apk/debug/app-debug.apk"},
+		{
+			name:         "Flutter iOS",
+			files:        map[string]string{"pubspec.yaml": "", "ios/Runner.xcworkspace/": "", "android/build.gradle": ""},
+			wantCommands: []string{"flutter pub get", "flutter build ios --simulator --debug"},
+			wantAppPath:  "build/ios/iphonesimulator/Runner.app"},
+		{
+			name:         "Flutter Android",
+			files:        map[string]string{"pubspec.yaml": "", "ios/Runner.xcworkspace/": "", "android/build.gradle": ""},
+			platform:     "android",
+			wantCommands: []string{"flutter pub get", "flutter build apk --debug"},
+			wantAppPath:  "build/app/outputs/flutter-apk/app-debug.apk"},
+		{
+			name:         "React Native iOS",
+			files:        map[string]string{"package.json": `{"dependencies": {"react-native": "0.74.0"}}`, "ios/Sample.xcworkspace/": "", "android/gradlew": ""},
+			wantCommands: []string{"npm ci", "(cd ios && pod install)", `xcodebuild -workspace "ios/Sample.xcworkspace" -scheme "Sample"`},
+			wantAppPath:  "build/waldo/Build/Products/Release-iphonesimulator/Sample.app"},
+		{
+			name:         "React Native Android",
+			files:        map[string]string{"package.json": `{"devDependencies": {"expo": "51.0.0"}}`, "ios/Sample.xcworkspace/": "", "android/gradlew": ""},
+			platform:     "android",
+			wantCommands: []string{"npm ci", "(cd android && ./gradlew assembleDebug)"},
+			wantAppPath:  "android/app/build/outputs/apk/debug/app-debug.apk"},
+	}
+
+	for _, providerName := range lib.SortedKeys(ciProviders) {
+		provider := ciProviders[providerName]
+
+		for _, project := range projects {
+			t.Run(providerName+"/"+project.name, func(t *testing.T) {
+				chdirTestProject(t, project.files)
+
+				options := &CIInitOptions{
+					Platform: project.platform,
+					Provider: providerName,
+					Trigger:  true}
+
+				ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+
+				if err := NewCIInitAction(options, ioStreams).Perform(); err != nil {
+					t.Fatalf("Perform() error = %v", err)
+				}
+
+				data, err := os.ReadFile(provider.outputPath)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				workflow := string(data)
+
+				for _, want := range append(project.wantCommands, `waldo upload "`+project.wantAppPath+`"`, "waldo trigger") {
+					if !strings.Contains(workflow, want) {
+						t.Errorf("workflow does not contain %q:\n%v", want, workflow)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCIInitActionPerformExisting(t *testing.T) {
+	const existing = "# existing pipeline\n"
+
+	for _, providerName := range lib.SortedKeys(ciProviders) {
+		provider := ciProviders[providerName]
+
+		for _, force := range []bool{false, true} {
+			name := providerName
+
+			if force {
+				name += "/force"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				chdirTestProject(t, map[string]string{"gradlew": "", provider.outputPath: existing})
+
+				var stdout strings.Builder
+
+				options := &CIInitOptions{
+					Force:    force,
+					Provider: providerName}
+
+				ioStreams := lib.NewIOStreams(strings.NewReader(""), &stdout, io.Discard)
+
+				err := NewCIInitAction(options, ioStreams).Perform()
+
+				data, _ := os.ReadFile(provider.outputPath)
+
+				switch {
+				case provider.shared:
+					if err != nil {
+						t.Fatalf("Perform() error = %v", err)
+					}
+
+					if string(data) != existing {
+						t.Errorf("shared file overwritten: %q", data)
+					}
+
+					if !strings.Contains(stdout.String(), "merge this") || !strings.Contains(stdout.String(), "./gradlew assembleDebug") {
+						t.Errorf("Perform() output = %q, want workflow to merge", stdout.String())
+					}
+
+				case force:
+					if err != nil {
+						t.Fatalf("Perform() error = %v", err)
+					}
+
+					if !strings.Contains(string(data), "./gradlew assembleDebug") {
+						t.Errorf("file not overwritten: %q", data)
+					}
+
+				default:
+					if err == nil || !strings.Contains(err.Error(), "use --force") {
+						t.Errorf("Perform() error = %v, want already exists", err)
+					}
+
+					if string(data) != existing {
+						t.Errorf("file overwritten without --force: %q", data)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestCIInitActionWriteWorkflowExists(t *testing.T) {
+	chdirTestProject(t, map[string]string{"bitrise.yml": "# created meanwhile\n"})
+
+	ioStreams := lib.NewIOStreams(strings.NewReader(""), io.Discard, io.Discard)
+	action := NewCIInitAction(&CIInitOptions{Force: true}, ioStreams)
+
+	//
+	// Even with --force, a shared file created after the existence check is
+	// not replaced:
+	//
+	if err := action.writeWorkflow(ciProviders["bitrise"], "workflow"); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("writeWorkflow() error = %v, want already exists", err)
+	}
+
+	if data, _ := os.ReadFile("bitrise.yml"); string(data) != "# created meanwhile\n" {
+		t.Errorf("bitrise.yml = %q", data)
+	}
+}